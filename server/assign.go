@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// newAssignAction returns the "Assign to me" button for the confirmation of the given issue.
+func newAssignAction(owner, repo string, number int) *model.PostAction {
+	return &model.PostAction{
+		Name: "Assign to me",
+		Integration: &model.PostActionIntegration{
+			URL: "/plugins/" + manifest.ID + "/assign",
+			Context: map[string]interface{}{
+				"owner":        owner,
+				"repo":         repo,
+				"issue_number": number,
+			},
+		},
+	}
+}
+
+// getAuthorGitHubUsername returns the GitHub username of the author of a marked post when
+// AssignToAuthor is set, from AssignToAuthorMapping or else their connected GitHub account. It
+// returns an empty string if the author has neither, in which case the issue is not assigned to
+// them. Failures are logged rather than returned, as the issue can be filed without the author.
+func (p *Plugin) getAuthorGitHubUsername(ctx context.Context, config *configuration, authorID string, logFields []interface{}) string {
+	if !config.AssignToAuthor || authorID == "" || authorID == p.botUserID {
+		return ""
+	}
+
+	author, appErr := p.API.GetUser(authorID)
+	if appErr != nil {
+		p.API.LogWarn("Unable to get post author", withLogFields(logFields, "author_id", authorID, "error", appErr.Error())...)
+		return ""
+	}
+	if githubUsername := config.getMappedGitHubUsername(author.Username); githubUsername != "" {
+		return githubUsername
+	}
+
+	if !config.isGitHub() {
+		return ""
+	}
+	client, err := p.getUserGitHubClient(authorID)
+	if err != nil {
+		p.API.LogWarn("Unable to create GitHub client for post author", withLogFields(logFields, "author_id", authorID, "error", err.Error())...)
+		return ""
+	}
+	if client == nil {
+		return ""
+	}
+
+	ctx, cancel := p.githubRequestContext(ctx)
+	defer cancel()
+
+	githubUser, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		p.API.LogWarn("Unable to get GitHub user of post author", withLogFields(logFields, "author_id", authorID, "error", err.Error())...)
+		return ""
+	}
+	return githubUser.GetLogin()
+}
+
+// handleAssign assigns the user clicking the "Assign to me" button to the issue through their
+// connected GitHub account, replying ephemerally with the outcome.
+func (p *Plugin) handleAssign(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request *model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	owner, _ := request.Context["owner"].(string)
+	repo, _ := request.Context["repo"].(string)
+	number, _ := request.Context["issue_number"].(float64)
+	if owner == "" || repo == "" || number < 1 {
+		http.Error(w, "owner, repo and issue_number are required", http.StatusBadRequest)
+		return
+	}
+	logFields := []interface{}{"user_id", userID, "repo", owner + "/" + repo, "issue", int(number)}
+
+	client, err := p.getUserGitHubClient(userID)
+	if err != nil {
+		p.API.LogError("Unable to create GitHub client for user", withLogFields(logFields, "error", err.Error())...)
+		p.respondAssign(w, userID, "Unable to assign you to the issue. Please try again later.")
+		return
+	}
+	if client == nil {
+		p.respondAssign(w, userID, "Connect your GitHub account with `/docup connect` to assign yourself to documentation issues.")
+		return
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	githubUser, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		p.API.LogError("Unable to get GitHub user", withLogFields(logFields, "error", err.Error())...)
+		p.respondAssign(w, userID, "Unable to assign you to the issue. Please try again later.")
+		return
+	}
+
+	issue, _, err := client.Issues.AddAssignees(ctx, owner, repo, int(number), []string{githubUser.GetLogin()})
+	if err != nil {
+		p.API.LogError("Unable to assign GitHub issue", withLogFields(logFields, "error", err.Error())...)
+		p.respondAssign(w, userID, "Unable to assign you to the issue. Please try again later.")
+		return
+	}
+
+	p.respondAssign(w, userID, fmt.Sprintf("Assigned you to [#%d](%s).", issue.GetNumber(), issue.GetHTMLURL()))
+}
+
+// respondAssign replies to the user clicking the "Assign to me" button with an ephemeral message.
+func (p *Plugin) respondAssign(w http.ResponseWriter, userID, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&model.PostActionIntegrationResponse{EphemeralText: message}); err != nil {
+		p.API.LogError("Unable to encode JSON", "user_id", userID, "error", err.Error())
+	}
+}
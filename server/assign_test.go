@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssign(t *testing.T) {
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v3/user":
+			_, _ = w.Write([]byte(`{"login": "octocat"}`))
+		case "POST /api/v3/repos/owner/repo/issues/1/assignees":
+			var body struct {
+				Assignees []string `json:"assignees"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, []string{"octocat"}, body.Assignees)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number": 1, "html_url": "https://github.com/owner/repo/issues/1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer github.Close()
+
+	for name, tc := range map[string]struct {
+		UserID          string
+		ExpectedMessage string
+	}{
+		"connected user": {
+			UserID:          "user1",
+			ExpectedMessage: "Assigned you to [#1](https://github.com/owner/repo/issues/1).",
+		},
+		"user without GitHub account": {
+			UserID:          "user2",
+			ExpectedMessage: "Connect your GitHub account with `/docup connect` to assign yourself to documentation issues.",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := map[string][]byte{}
+
+			api := &plugintest.API{}
+			api.On("KVSet", "token_user1", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				store[args.String(0)] = args.Get(1).([]byte)
+			})
+			api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+				return store[key]
+			}, nil)
+
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(&configuration{
+				GitHubBaseURL:           github.URL + "/api/v3/",
+				GitHubOAuthClientID:     "id",
+				GitHubOAuthClientSecret: "secret",
+				EncryptionKey:           "key",
+			})
+			require.NoError(t, plugin.saveUserToken("user1", &oauth2.Token{AccessToken: "personal"}))
+
+			body, err := json.Marshal(&model.PostActionIntegrationRequest{
+				UserId:  tc.UserID,
+				Context: newAssignAction("owner", "repo", 1).Integration.Context,
+			})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/assign", bytes.NewBuffer(body))
+			r.Header.Set("Mattermost-User-ID", tc.UserID)
+
+			plugin.ServeHTTP(nil, w, r)
+
+			result := w.Result()
+			assert.Equal(t, http.StatusOK, result.StatusCode)
+
+			var response model.PostActionIntegrationResponse
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&response))
+			assert.Equal(t, tc.ExpectedMessage, response.EphemeralText)
+		})
+	}
+}
+
+func TestGetAuthorGitHubUsername(t *testing.T) {
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method+" "+r.URL.Path != "GET /api/v3/user" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "Bearer personal", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"login": "octocat"}`))
+	}))
+	defer github.Close()
+
+	for name, tc := range map[string]struct {
+		AssignToAuthor bool
+		AuthorID       string
+		Expected       string
+	}{
+		"disabled": {
+			AuthorID: "user1",
+		},
+		"mapped author": {
+			AssignToAuthor: true,
+			AuthorID:       "user2",
+			Expected:       "mapped-user",
+		},
+		"connected author": {
+			AssignToAuthor: true,
+			AuthorID:       "user1",
+			Expected:       "octocat",
+		},
+		"author without GitHub account": {
+			AssignToAuthor: true,
+			AuthorID:       "user3",
+		},
+		"bot author": {
+			AssignToAuthor: true,
+			AuthorID:       "bot1",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := map[string][]byte{}
+
+			api := &plugintest.API{}
+			api.On("KVSet", "token_user1", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				store[args.String(0)] = args.Get(1).([]byte)
+			})
+			api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+				return store[key]
+			}, nil)
+			api.On("GetUser", mock.AnythingOfType("string")).Return(func(userID string) *model.User {
+				return &model.User{Id: userID, Username: "name-of-" + userID}
+			}, nil)
+
+			plugin := Plugin{botUserID: "bot1"}
+			plugin.SetAPI(api)
+			config := &configuration{
+				GitHubBaseURL:           github.URL + "/api/v3/",
+				GitHubOAuthClientID:     "id",
+				GitHubOAuthClientSecret: "secret",
+				EncryptionKey:           "key",
+				AssignToAuthor:          tc.AssignToAuthor,
+				AssignToAuthorMapping:   `{"name-of-user2": " mapped-user "}`,
+			}
+			plugin.setConfiguration(config)
+			require.NoError(t, plugin.saveUserToken("user1", &oauth2.Token{AccessToken: "personal"}))
+
+			assert.Equal(t, tc.Expected, plugin.getAuthorGitHubUsername(context.Background(), config, tc.AuthorID, nil))
+		})
+	}
+}
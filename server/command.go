@@ -0,0 +1,510 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin"
+)
+
+const commandTrigger = "docup"
+
+const commandHelp = "###### Doc Up - Slash Command Help\n" +
+	"`<type>` is one of `admin`, `developer`, `handbook` or `feature`.\n\n" +
+	"* `/docup <type> <title>` - Create a documentation issue for the post you are replying to.\n" +
+	"* `/docup status [type] <issue-number>` - Show the state of an issue in the repository for this channel or its team, or for `[type]` if given.\n" +
+	"* `/docup list [type]` - List the latest documentation requests in the repository for this channel, or for `[type]` if the channel has none.\n" +
+	"* `/docup mine` - List the documentation requests you filed most recently, with their current state.\n" +
+	"* `/docup connect` - Connect your GitHub account to file issues as yourself or assign them to yourself.\n" +
+	"* `/docup setup <owner/repo> [type]` - File issues of `[type]`, or of the default type, in `<owner/repo>`. Only available to system admins.\n" +
+	"* `/docup test [type] [close]` - File a test issue for `[type]`, or for the default type, and check it can be read back, closing it with `close`. Only available to system admins.\n"
+
+func getCommand() *model.Command {
+	return &model.Command{
+		Trigger:          commandTrigger,
+		DisplayName:      "Doc Up",
+		Description:      "Mark a post for documentation.",
+		AutoComplete:     true,
+		AutoCompleteDesc: "Mark the post you are replying to for documentation. Available commands: <type> <title>, status, list, mine, connect, setup, test, help",
+		AutoCompleteHint: "[command]",
+	}
+}
+
+func getCommandResponse(text string) *model.CommandResponse {
+	return &model.CommandResponse{
+		ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL,
+		Text:         text,
+		Username:     "Doc Up",
+	}
+}
+
+// ExecuteCommand handles the /docup slash command.
+func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	split := strings.Fields(args.Command)
+	if len(split) == 0 || split[0] != "/"+commandTrigger {
+		return &model.CommandResponse{}, nil
+	}
+
+	if len(split) < 2 || split[1] == "help" {
+		return getCommandResponse(commandHelp), nil
+	}
+
+	switch split[1] {
+	case "status":
+		return p.executeStatusCommand(args.ChannelId, split[2:]), nil
+	case "list":
+		return p.executeListCommand(args.ChannelId, split[2:]), nil
+	case "mine":
+		return p.executeMineCommand(args.UserId, split[2:]), nil
+	case "connect":
+		return p.executeConnectCommand(), nil
+	case "setup":
+		return p.executeSetupCommand(args.UserId, split[2:]), nil
+	case "test":
+		return p.executeTestCommand(args.UserId, split[2:]), nil
+	}
+
+	if len(split) < 3 {
+		return getCommandResponse(commandHelp), nil
+	}
+
+	postID := args.ParentId
+	if postID == "" {
+		postID = args.RootId
+	}
+	if postID == "" {
+		return getCommandResponse("Please run `/docup` as a reply to the post you want documented."), nil
+	}
+
+	docPost, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		p.API.LogError("Unable to get post err=" + appErr.Error())
+		return getCommandResponse("Unable to find the post to document."), nil
+	}
+
+	createResponse, err := p.createIssueFromPost(p.pluginContext(), args.UserId, &CreateAPIRequest{
+		Type:   split[1],
+		Title:  strings.Join(split[2:], " "),
+		Body:   docPost.Message,
+		PostID: docPost.Id,
+	})
+	p.metrics.recordCreate(createResponse, err)
+	if err != nil {
+		return getCommandResponse("Unable to create the documentation issue: " + err.Error()), nil
+	}
+
+	if createResponse.Existing {
+		return getCommandResponse(fmt.Sprintf("Updated existing documentation issue [#%d](%s).", createResponse.IssueNumber, createResponse.IssueURL)), nil
+	}
+
+	return getCommandResponse(fmt.Sprintf("Created documentation issue [#%d](%s).", createResponse.IssueNumber, createResponse.IssueURL)), nil
+}
+
+// getCommandRepository resolves the repository a command run in the given channel applies to: the
+// channel's repository if it has one, or else the team's repository for the issue type, or else
+// the default repository of the issue type. If the repository cannot be resolved, a message
+// explaining why is returned instead.
+func (p *Plugin) getCommandRepository(config *configuration, channelID, issueType string) (string, string, string) {
+	ownerAndRepo := config.getChannelRepository(channelID)
+	if ownerAndRepo == "" {
+		if issueType == "" {
+			return "", "", "This channel has no repository, please specify a documentation type."
+		}
+		return p.getCommandTypeRepository(config, channelID, issueType)
+	}
+
+	return p.splitCommandRepository(ownerAndRepo)
+}
+
+// getCommandTypeRepository resolves the repository of the given issue type for a command run in
+// the given channel, ignoring the channel's repository: the team's repository for the issue type,
+// or else its default repository.
+func (p *Plugin) getCommandTypeRepository(config *configuration, channelID, issueType string) (string, string, string) {
+	ownerAndRepo := p.resolveTeamRepository(config, channelID, issueType, []interface{}{"channel_id", channelID})
+	if ownerAndRepo == "" {
+		repositories := config.getRepositories(issueType)
+		if len(repositories) == 0 {
+			return "", "", fmt.Sprintf("Unknown documentation type `%s`.", issueType)
+		}
+		ownerAndRepo = repositories[0]
+	}
+
+	return p.splitCommandRepository(ownerAndRepo)
+}
+
+// splitCommandRepository splits a configured owner/repo, returning a message for the command's
+// response instead if it is misconfigured.
+func (p *Plugin) splitCommandRepository(ownerAndRepo string) (string, string, string) {
+	owner, repo, err := splitOwnerAndRepo(ownerAndRepo)
+	if err != nil {
+		p.API.LogError("Bad configured repo: " + ownerAndRepo)
+		return "", "", "The repository for this documentation type is misconfigured."
+	}
+
+	return owner, repo, ""
+}
+
+// executeStatusCommand shows the state of an issue in the repository of the channel, or in the
+// repository of the given type.
+func (p *Plugin) executeStatusCommand(channelID string, parameters []string) *model.CommandResponse {
+	if len(parameters) < 1 || len(parameters) > 2 {
+		return getCommandResponse("Please use `/docup status [type] <issue-number>`.")
+	}
+
+	rawNumber := parameters[len(parameters)-1]
+	number, err := strconv.Atoi(strings.TrimPrefix(rawNumber, "#"))
+	if err != nil {
+		return getCommandResponse(fmt.Sprintf("`%s` is not a valid issue number.", rawNumber))
+	}
+
+	config := p.getConfiguration()
+	if !config.isGitHub() {
+		return getCommandResponse("`/docup status` is only available when issues are filed on GitHub.")
+	}
+
+	// A type overrides the channel's repository, while without one the default type is used for
+	// channels whose team, rather than the channel itself, has a repository.
+	var owner, repo, message string
+	if len(parameters) == 2 {
+		owner, repo, message = p.getCommandTypeRepository(config, channelID, parameters[0])
+	} else {
+		owner, repo, message = p.getCommandRepository(config, channelID, config.DefaultType)
+	}
+	if message != "" {
+		return getCommandResponse(message)
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	issue, resp, err := p.getGitHubClient().Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return getCommandResponse(fmt.Sprintf("Issue #%d does not exist in %s/%s.", number, owner, repo))
+		}
+		p.API.LogError("Error getting GitHub issue err=" + err.Error())
+		return getCommandResponse("Unable to get the issue from GitHub.")
+	}
+
+	assignees := []string{}
+	for _, assignee := range issue.Assignees {
+		assignees = append(assignees, assignee.GetLogin())
+	}
+	if len(assignees) == 0 {
+		assignees = append(assignees, "none")
+	}
+
+	labels := []string{}
+	for _, label := range issue.Labels {
+		labels = append(labels, label.GetName())
+	}
+	if len(labels) == 0 {
+		labels = append(labels, "none")
+	}
+
+	return getCommandResponse(fmt.Sprintf("[#%d %s](%s)\n* State: %s\n* Assignees: %s\n* Labels: %s",
+		issue.GetNumber(),
+		issue.GetTitle(),
+		issue.GetHTMLURL(),
+		issue.GetState(),
+		strings.Join(assignees, ", "),
+		strings.Join(labels, ", "),
+	))
+}
+
+func (p *Plugin) executeConnectCommand() *model.CommandResponse {
+	config := p.getConfiguration()
+	if !config.canConnectGitHub() {
+		return getCommandResponse("Connecting your GitHub account is not enabled, issues are filed by a shared account.")
+	}
+
+	siteURL, err := p.getSiteURL()
+	if err != nil {
+		p.API.LogError("Unable to get site URL", "error", err.Error())
+		return getCommandResponse("Unable to link to your GitHub account: " + err.Error())
+	}
+	siteURL = strings.TrimSuffix(siteURL, "/")
+	return getCommandResponse(fmt.Sprintf("[Click here to connect your GitHub account](%s/plugins/%s/oauth/connect).", siteURL, manifest.ID))
+}
+
+func (p *Plugin) executeSetupCommand(userID string, parameters []string) *model.CommandResponse {
+	if !p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM) {
+		return getCommandResponse("Only system admins can set up repositories.")
+	}
+
+	if len(parameters) < 1 || len(parameters) > 2 {
+		return getCommandResponse("Please use `/docup setup <owner/repo> [type]`.")
+	}
+
+	owner, repo, err := splitOwnerAndRepo(parameters[0])
+	if err != nil {
+		return getCommandResponse(fmt.Sprintf("`%s` is not in owner/repo form.", parameters[0]))
+	}
+
+	config := p.getConfiguration()
+	if !config.isGitHub() {
+		return getCommandResponse("`/docup setup` is only available when issues are filed on GitHub.")
+	}
+
+	issueType := config.DefaultType
+	if len(parameters) == 2 {
+		issueType = parameters[1]
+	}
+	if issueType == "" {
+		return getCommandResponse("There is no default documentation type, please specify one.")
+	}
+	setting, ok := repositorySettings[issueType]
+	if !ok {
+		return getCommandResponse(fmt.Sprintf("Unknown documentation type `%s`.", issueType))
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	repository, resp, err := p.getGitHubClient().Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return getCommandResponse(fmt.Sprintf("Repository %s/%s does not exist or is not accessible with the configured credentials.", owner, repo))
+		}
+		p.API.LogError("Error getting GitHub repository err=" + err.Error())
+		return getCommandResponse("Unable to get the repository from GitHub.")
+	}
+
+	if !repository.GetHasIssues() {
+		return getCommandResponse(fmt.Sprintf("Issues are disabled in %s/%s, enable them in the repository settings.", owner, repo))
+	}
+
+	// Installation tokens of GitHub Apps carry no repository permissions, which are then checked
+	// when issues are created.
+	required := []string{"pull"}
+	if config.getMode() == modePRStub {
+		required = append(required, "push")
+	}
+	if permissions := repository.GetPermissions(); permissions != nil {
+		for _, permission := range required {
+			if !permissions[permission] {
+				return getCommandResponse(fmt.Sprintf("The configured credentials are missing the `%s` permission on %s/%s.", permission, owner, repo))
+			}
+		}
+	}
+
+	repositories := []string{owner + "/" + repo}
+	for _, ownerAndRepo := range config.getRepositories(issueType) {
+		if !strings.EqualFold(ownerAndRepo, repositories[0]) {
+			repositories = append(repositories, ownerAndRepo)
+		}
+	}
+
+	pluginConfig := p.API.GetPluginConfig()
+	if pluginConfig == nil {
+		pluginConfig = map[string]interface{}{}
+	}
+	// The server may have stored the setting under a lowercased key.
+	for key := range pluginConfig {
+		if strings.EqualFold(key, setting) {
+			delete(pluginConfig, key)
+		}
+	}
+	pluginConfig[setting] = strings.Join(repositories, ", ")
+
+	if appErr := p.API.SavePluginConfig(pluginConfig); appErr != nil {
+		p.API.LogError("Unable to save plugin config err=" + appErr.Error())
+		return getCommandResponse("Unable to save the plugin configuration.")
+	}
+
+	return getCommandResponse(fmt.Sprintf("Issues of type `%s` are now filed in %s/%s.", issueType, owner, repo))
+}
+
+// testIssueTitle is the title of the issues filed by /docup test.
+const testIssueTitle = "Doc Up test issue"
+
+// executeTestCommand files a test issue in the repository of an issue type and reads it back,
+// checking end to end that the plugin can file documentation issues.
+func (p *Plugin) executeTestCommand(userID string, parameters []string) *model.CommandResponse {
+	if !p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM) {
+		return getCommandResponse("Only system admins can test issue creation.")
+	}
+
+	closeIssue := len(parameters) > 0 && parameters[len(parameters)-1] == "close"
+	if closeIssue {
+		parameters = parameters[:len(parameters)-1]
+	}
+	if len(parameters) > 1 {
+		return getCommandResponse("Please use `/docup test [type] [close]`.")
+	}
+
+	config := p.getConfiguration()
+	if !config.isGitHub() {
+		return getCommandResponse("`/docup test` is only available when issues are filed on GitHub.")
+	}
+
+	issueType := config.DefaultType
+	if len(parameters) == 1 {
+		issueType = parameters[0]
+	}
+	if issueType == "" {
+		return getCommandResponse("There is no default documentation type, please specify one.")
+	}
+	repositories := config.getRepositories(issueType)
+	if len(repositories) == 0 {
+		return getCommandResponse(fmt.Sprintf("Unknown documentation type `%s`.", issueType))
+	}
+
+	owner, repo, err := splitOwnerAndRepo(repositories[0])
+	if err != nil {
+		return getCommandResponse("The repository for this documentation type is misconfigured.")
+	}
+
+	issueRequest := &github.IssueRequest{
+		Title: NewString(testIssueTitle),
+		Body:  NewString("This issue was filed by a system admin running `/docup test` to check that Doc Up can file documentation issues. It can safely be closed."),
+	}
+	if label := config.getIdentifierLabel(); label != "" {
+		issueRequest.Labels = &[]string{label}
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	client := p.getGitHubClient()
+	issue, err := p.createIssue(ctx, client, owner, repo, issueRequest)
+	if err != nil {
+		return getCommandResponse(fmt.Sprintf("Unable to create a test issue in %s/%s: %s", owner, repo, err.Error()))
+	}
+
+	fetched, _, err := client.Issues.Get(ctx, owner, repo, issue.GetNumber())
+	if err != nil {
+		return getCommandResponse(fmt.Sprintf("Created test issue [#%d](%s) in %s/%s, but unable to read it back: %s", issue.GetNumber(), issue.GetHTMLURL(), owner, repo, err.Error()))
+	}
+	if fetched.GetTitle() != testIssueTitle {
+		return getCommandResponse(fmt.Sprintf("Created test issue [#%d](%s) in %s/%s, but read back an issue titled `%s`.", issue.GetNumber(), issue.GetHTMLURL(), owner, repo, fetched.GetTitle()))
+	}
+
+	if !closeIssue {
+		return getCommandResponse(fmt.Sprintf("Issue creation works: created test issue [#%d](%s) in %s/%s and read it back.", issue.GetNumber(), issue.GetHTMLURL(), owner, repo))
+	}
+
+	if _, _, err := client.Issues.Edit(ctx, owner, repo, issue.GetNumber(), &github.IssueRequest{State: NewString("closed")}); err != nil {
+		return getCommandResponse(fmt.Sprintf("Issue creation works: created test issue [#%d](%s) in %s/%s and read it back, but unable to close it: %s", issue.GetNumber(), issue.GetHTMLURL(), owner, repo, err.Error()))
+	}
+
+	return getCommandResponse(fmt.Sprintf("Issue creation works: created test issue [#%d](%s) in %s/%s, read it back and closed it.", issue.GetNumber(), issue.GetHTMLURL(), owner, repo))
+}
+
+// maxListedIssues caps the number of issues shown by /docup list.
+const maxListedIssues = 10
+
+func (p *Plugin) executeListCommand(channelID string, parameters []string) *model.CommandResponse {
+	if len(parameters) > 1 {
+		return getCommandResponse("Please use `/docup list [type]`.")
+	}
+
+	issueType := ""
+	if len(parameters) == 1 {
+		issueType = parameters[0]
+	}
+
+	config := p.getConfiguration()
+	if !config.isGitHub() {
+		return getCommandResponse("`/docup list` is only available when issues are filed on GitHub.")
+	}
+
+	owner, repo, message := p.getCommandRepository(config, channelID, issueType)
+	if message != "" {
+		return getCommandResponse(message)
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	query := fmt.Sprintf("repo:%s/%s is:issue in:body %q", owner, repo, config.getIssueMarkerText())
+	result, _, err := p.getGitHubClient().Search.Issues(ctx, query, &github.SearchOptions{
+		Sort:        "created",
+		Order:       "desc",
+		ListOptions: github.ListOptions{PerPage: maxListedIssues},
+	})
+	if err != nil {
+		p.API.LogError("Error searching GitHub issues err=" + err.Error())
+		return getCommandResponse("Unable to search for issues on GitHub.")
+	}
+
+	rows := []string{}
+	for _, issue := range result.Issues {
+		if !strings.Contains(issue.GetBody(), config.getIssueMarker()) {
+			continue
+		}
+		rows = append(rows, fmt.Sprintf("| [#%d](%s) | %s | %s |",
+			issue.GetNumber(),
+			issue.GetHTMLURL(),
+			strings.Replace(issue.GetTitle(), "|", "\\|", -1),
+			issue.GetState(),
+		))
+		if len(rows) == maxListedIssues {
+			break
+		}
+	}
+
+	if len(rows) == 0 {
+		return getCommandResponse(fmt.Sprintf("No documentation requests have been filed in %s/%s yet.", owner, repo))
+	}
+
+	return getCommandResponse(fmt.Sprintf("Latest documentation requests in %s/%s:\n\n| Issue | Title | State |\n| --- | --- | --- |\n%s",
+		owner,
+		repo,
+		strings.Join(rows, "\n"),
+	))
+}
+
+func (p *Plugin) executeMineCommand(userID string, parameters []string) *model.CommandResponse {
+	if len(parameters) != 0 {
+		return getCommandResponse("Please use `/docup mine`.")
+	}
+
+	config := p.getConfiguration()
+	if !config.isGitHub() {
+		return getCommandResponse("`/docup mine` is only available when issues are filed on GitHub.")
+	}
+
+	history, err := p.getUserHistory(userID)
+	if err != nil {
+		p.API.LogError("Unable to get user history err=" + err.Error())
+		return getCommandResponse("Unable to get your documentation requests.")
+	}
+	if len(history) == 0 {
+		return getCommandResponse("You have not filed any documentation requests yet.")
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	client := p.getGitHubClient()
+	rows := []string{}
+	for _, entry := range history {
+		state := "unknown"
+		if owner, repo, err := splitOwnerAndRepo(entry.Repository); err == nil {
+			issue, _, err := client.Issues.Get(ctx, owner, repo, entry.IssueNumber)
+			if err != nil {
+				p.API.LogWarn("Error getting GitHub issue err=" + err.Error())
+			} else {
+				state = issue.GetState()
+			}
+		}
+		rows = append(rows, fmt.Sprintf("| [%s#%d](%s) | %s | %s | %s |",
+			entry.Repository,
+			entry.IssueNumber,
+			entry.IssueURL,
+			strings.Replace(entry.Title, "|", "\\|", -1),
+			time.Unix(entry.CreatedAt, 0).UTC().Format("2006-01-02"),
+			state,
+		))
+	}
+
+	return getCommandResponse(fmt.Sprintf("Your latest documentation requests:\n\n| Issue | Title | Filed | State |\n| --- | --- | --- | --- |\n%s",
+		strings.Join(rows, "\n"),
+	))
+}
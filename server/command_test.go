@@ -0,0 +1,335 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExecuteSetupCommand(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Command          string
+		IsAdmin          bool
+		Repository       string
+		ExpectedResponse string
+		ExpectedConfig   map[string]interface{}
+	}{
+		"not a system admin": {
+			Command:          "/docup setup owner/docs admin",
+			ExpectedResponse: "Only system admins can set up repositories.",
+		},
+		"invalid repository": {
+			Command:          "/docup setup docs admin",
+			IsAdmin:          true,
+			ExpectedResponse: "`docs` is not in owner/repo form.",
+		},
+		"no default type": {
+			Command:          "/docup setup owner/docs",
+			IsAdmin:          true,
+			ExpectedResponse: "There is no default documentation type, please specify one.",
+		},
+		"repository not found": {
+			Command:          "/docup setup owner/missing admin",
+			IsAdmin:          true,
+			ExpectedResponse: "Repository owner/missing does not exist or is not accessible with the configured credentials.",
+		},
+		"issues disabled": {
+			Command:          "/docup setup owner/docs admin",
+			IsAdmin:          true,
+			Repository:       `{"has_issues": false, "permissions": {"pull": true}}`,
+			ExpectedResponse: "Issues are disabled in owner/docs, enable them in the repository settings.",
+		},
+		"missing permission": {
+			Command:          "/docup setup owner/docs admin",
+			IsAdmin:          true,
+			Repository:       `{"has_issues": true, "permissions": {"pull": false}}`,
+			ExpectedResponse: "The configured credentials are missing the `pull` permission on owner/docs.",
+		},
+		"repository saved": {
+			Command:          "/docup setup owner/docs admin",
+			IsAdmin:          true,
+			Repository:       `{"has_issues": true, "permissions": {"pull": true}}`,
+			ExpectedResponse: "Issues of type `admin` are now filed in owner/docs.",
+			ExpectedConfig: map[string]interface{}{
+				"AdminRepository":     "owner/docs, owner/admin",
+				"developerrepository": "owner/developer",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			api.On("HasPermissionTo", "user1", model.PERMISSION_MANAGE_SYSTEM).Return(tc.IsAdmin)
+			if tc.ExpectedConfig != nil {
+				api.On("GetPluginConfig").Return(map[string]interface{}{
+					"adminrepository":     "owner/admin",
+					"developerrepository": "owner/developer",
+				})
+				api.On("SavePluginConfig", tc.ExpectedConfig).Return(nil)
+			}
+			defer api.AssertExpectations(t)
+
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(&configuration{AdminRepository: "owner/admin", DeveloperRepository: "owner/developer"})
+			plugin.github = newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/owner/docs" || tc.Repository == "" {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+					return
+				}
+				_, _ = w.Write([]byte(tc.Repository))
+			})
+
+			response, appErr := plugin.ExecuteCommand(nil, &model.CommandArgs{UserId: "user1", ChannelId: "channel1", Command: tc.Command})
+
+			assert.Nil(appErr)
+			assert.Equal(tc.ExpectedResponse, response.Text)
+		})
+	}
+}
+
+func TestExecuteTestCommand(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Command          string
+		IsAdmin          bool
+		CreateStatus     int
+		ExpectedCalls    []string
+		ExpectedResponse string
+	}{
+		"not a system admin": {
+			Command:          "/docup test admin",
+			ExpectedResponse: "Only system admins can test issue creation.",
+		},
+		"unknown type": {
+			Command:          "/docup test sales",
+			IsAdmin:          true,
+			ExpectedResponse: "Unknown documentation type `sales`.",
+		},
+		"issue created": {
+			Command:          "/docup test admin",
+			IsAdmin:          true,
+			CreateStatus:     http.StatusCreated,
+			ExpectedCalls:    []string{"POST /repos/owner/admin/issues", "GET /repos/owner/admin/issues/5"},
+			ExpectedResponse: "Issue creation works: created test issue [#5](https://github.com/owner/admin/issues/5) in owner/admin and read it back.",
+		},
+		"issue created and closed": {
+			Command:          "/docup test close",
+			IsAdmin:          true,
+			CreateStatus:     http.StatusCreated,
+			ExpectedCalls:    []string{"POST /repos/owner/admin/issues", "GET /repos/owner/admin/issues/5", "PATCH /repos/owner/admin/issues/5"},
+			ExpectedResponse: "Issue creation works: created test issue [#5](https://github.com/owner/admin/issues/5) in owner/admin, read it back and closed it.",
+		},
+		"issue not created": {
+			Command:          "/docup test admin",
+			IsAdmin:          true,
+			CreateStatus:     http.StatusGone,
+			ExpectedCalls:    []string{"POST /repos/owner/admin/issues"},
+			ExpectedResponse: "Unable to create a test issue in owner/admin: POST https://api.github.com/repos/owner/admin/issues: 410 Issues are disabled for this repo []",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			api.On("HasPermissionTo", "user1", model.PERMISSION_MANAGE_SYSTEM).Return(tc.IsAdmin)
+			defer api.AssertExpectations(t)
+
+			calls := []string{}
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(&configuration{AdminRepository: "owner/admin", DefaultType: "admin"})
+			plugin.github = newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.Method+" "+r.URL.Path)
+				switch r.Method {
+				case http.MethodPost:
+					w.WriteHeader(tc.CreateStatus)
+					if tc.CreateStatus != http.StatusCreated {
+						_, _ = w.Write([]byte(`{"message": "Issues are disabled for this repo"}`))
+						return
+					}
+				case http.MethodPatch:
+					body, _ := ioutil.ReadAll(r.Body)
+					assert.Contains(string(body), `"state":"closed"`)
+				}
+				_, _ = w.Write([]byte(`{"number": 5, "title": "Doc Up test issue", "html_url": "https://github.com/owner/admin/issues/5"}`))
+			})
+
+			response, appErr := plugin.ExecuteCommand(nil, &model.CommandArgs{UserId: "user1", ChannelId: "channel1", Command: tc.Command})
+
+			assert.Nil(appErr)
+			assert.Equal(tc.ExpectedResponse, response.Text)
+			if tc.ExpectedCalls == nil {
+				assert.Empty(calls)
+			} else {
+				assert.Equal(tc.ExpectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestExecuteMineCommand(t *testing.T) {
+	for name, tc := range map[string]struct {
+		History          []byte
+		ExpectedResponse string
+	}{
+		"no history": {
+			ExpectedResponse: "You have not filed any documentation requests yet.",
+		},
+		"history": {
+			History: []byte(`[{"repository":"owner/repo","issue_number":2,"issue_url":"https://github.com/owner/repo/issues/2","title":"Second | title","created_at":1500086400},` +
+				`{"repository":"owner/admin","issue_number":1,"issue_url":"https://github.com/owner/admin/issues/1","title":"First","created_at":1500000000}]`),
+			ExpectedResponse: "Your latest documentation requests:\n\n| Issue | Title | Filed | State |\n| --- | --- | --- | --- |\n" +
+				"| [owner/repo#2](https://github.com/owner/repo/issues/2) | Second \\| title | 2017-07-15 | open |\n" +
+				"| [owner/admin#1](https://github.com/owner/admin/issues/1) | First | 2017-07-14 | unknown |",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			api.On("KVGet", "history_user1").Return(tc.History, nil)
+			if tc.History != nil {
+				api.On("LogWarn", mock.AnythingOfType("string")).Return()
+			}
+			defer api.AssertExpectations(t)
+
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(&configuration{})
+			plugin.github = newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/owner/repo/issues/2" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write([]byte(`{"number": 2, "state": "open"}`))
+			})
+
+			response, appErr := plugin.ExecuteCommand(nil, &model.CommandArgs{UserId: "user1", ChannelId: "channel1", Command: "/docup mine"})
+
+			assert.Nil(appErr)
+			assert.Equal(tc.ExpectedResponse, response.Text)
+		})
+	}
+}
+
+func TestExecuteConnectCommand(t *testing.T) {
+	for name, tc := range map[string]struct {
+		SiteURL          *string
+		PreferUserToken  bool
+		ExpectedResponse string
+	}{
+		"connect link": {
+			SiteURL:          NewString("https://mattermost.example.com/"),
+			PreferUserToken:  true,
+			ExpectedResponse: "[Click here to connect your GitHub account](https://mattermost.example.com/plugins/" + manifest.ID + "/oauth/connect).",
+		},
+		"connect link for assigning issues only": {
+			SiteURL:          NewString("https://mattermost.example.com/"),
+			ExpectedResponse: "[Click here to connect your GitHub account](https://mattermost.example.com/plugins/" + manifest.ID + "/oauth/connect).",
+		},
+		"no site URL": {
+			PreferUserToken:  true,
+			ExpectedResponse: "Unable to link to your GitHub account: SiteURL is not configured",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: tc.SiteURL}})
+			if tc.SiteURL == nil {
+				api.On("LogError", logArguments(1)...).Return()
+			}
+			defer api.AssertExpectations(t)
+
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(&configuration{
+				PreferUserToken:         tc.PreferUserToken,
+				GitHubOAuthClientID:     "client",
+				GitHubOAuthClientSecret: "secret",
+				EncryptionKey:           "key",
+			})
+
+			response, appErr := plugin.ExecuteCommand(nil, &model.CommandArgs{UserId: "user1", ChannelId: "channel1", Command: "/docup connect"})
+
+			assert.Nil(appErr)
+			assert.Equal(tc.ExpectedResponse, response.Text)
+		})
+	}
+}
+
+func TestExecuteStatusCommand(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Command          string
+		Configuration    *configuration
+		ExpectedResponse string
+	}{
+		"issue in the channel's repository": {
+			Command:          "/docup status 7",
+			Configuration:    &configuration{AdminRepository: "owner/admin", ChannelRepositoryMap: `{"channel1": "owner/channel"}`},
+			ExpectedResponse: "[#7 Channel issue](https://github.com/owner/channel/issues/7)\n* State: open\n* Assignees: octocat\n* Labels: docs",
+		},
+		"issue in the team's repository": {
+			Command:          "/docup status #7",
+			Configuration:    &configuration{AdminRepository: "owner/admin", DefaultType: "admin", TeamRepositoryMap: `{"team1": {"admin": "team/admin"}}`},
+			ExpectedResponse: "[#7 Team issue](https://github.com/team/admin/issues/7)\n* State: closed\n* Assignees: none\n* Labels: none",
+		},
+		"type overrides the channel's repository": {
+			Command:          "/docup status admin 7",
+			Configuration:    &configuration{AdminRepository: "owner/admin", ChannelRepositoryMap: `{"channel1": "owner/channel"}`},
+			ExpectedResponse: "Issue #7 does not exist in owner/admin.",
+		},
+		"no repository for the channel": {
+			Command:          "/docup status 7",
+			Configuration:    &configuration{AdminRepository: "owner/admin"},
+			ExpectedResponse: "This channel has no repository, please specify a documentation type.",
+		},
+		"invalid issue number": {
+			Command:          "/docup status admin seven",
+			Configuration:    &configuration{AdminRepository: "owner/admin"},
+			ExpectedResponse: "`seven` is not a valid issue number.",
+		},
+		"no issue number": {
+			Command:          "/docup status",
+			Configuration:    &configuration{AdminRepository: "owner/admin"},
+			ExpectedResponse: "Please use `/docup status [type] <issue-number>`.",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			if tc.Configuration.TeamRepositoryMap != "" {
+				api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1"}, nil)
+			}
+			defer api.AssertExpectations(t)
+
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(tc.Configuration)
+			plugin.github = newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/owner/channel/issues/7":
+					_, _ = w.Write([]byte(`{"number": 7, "title": "Channel issue", "html_url": "https://github.com/owner/channel/issues/7", "state": "open", "assignees": [{"login": "octocat"}], "labels": [{"name": "docs"}]}`))
+				case "/repos/team/admin/issues/7":
+					_, _ = w.Write([]byte(`{"number": 7, "title": "Team issue", "html_url": "https://github.com/team/admin/issues/7", "state": "closed"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			response, appErr := plugin.ExecuteCommand(nil, &model.CommandArgs{UserId: "user1", ChannelId: "channel1", Command: tc.Command})
+
+			assert.Nil(appErr)
+			assert.Equal(tc.ExpectedResponse, response.Text)
+		})
+	}
+}
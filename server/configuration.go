@@ -1,8 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"net/url"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/mattermost/mattermost-server/model"
 	"github.com/pkg/errors"
 )
 
@@ -18,13 +25,280 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
+	Provider            string
 	GitHubAPIKey        string
+	GitHubBaseURL       string
+	GitHubProxyURL      string
+	GitLabURL           string
+	GitLabToken         string
+	GiteaURL            string
+	GiteaToken          string
 	AdminRepository     string
 	DeveloperRepository string
 	HandbookRepository  string
-	Labels              string
+	FeatureRepository   string
+
+	// Organization is a GitHub organization whose OrganizationRepository, documentation unless
+	// set, files the issues of types with no repository configured. Requests for those types may
+	// also name another repository of the organization.
+	Organization           string
+	OrganizationRepository string
+
+	// AuthMode selects whether GitHub is accessed with the GitHubAPIKey personal access token, or
+	// as the installation GitHubAppInstallationID of the GitHub App GitHubAppID, authenticated with
+	// the PEM encoded GitHubAppPrivateKey.
+	AuthMode                string
+	GitHubAppID             string
+	GitHubAppInstallationID string
+	GitHubAppPrivateKey     string
+
+	// DefaultType is the documentation type used for requests that do not specify one.
+	DefaultType string
+
+	// Mode is modeIssue to file an issue for each post marked for documentation, or modePRStub to
+	// open a pull request adding a placeholder document to DocsDirectory instead.
+	Mode          string
+	DocsDirectory string
+
+	// ChannelRepositoryMap is a JSON object mapping channel IDs to the owner/repo receiving the
+	// issues for posts in that channel, taking precedence over the repository of the issue type.
+	// A repository explicitly selected in the request still takes precedence over both.
+	ChannelRepositoryMap string
+
+	// TeamRepositoryMap is a JSON object mapping team IDs to objects mapping issue types to an
+	// owner/repo, routing issues for posts in that team to the team's repository. Channel
+	// repositories take precedence over team repositories.
+	TeamRepositoryMap string
+
+	TitlePrefix       string
+	BodyTemplate      string
+	Footer            string
+	Labels            string
+	Assignees         string
+	DeduplicateIssues bool
+	MaxRetries        string
+	GitHubTimeout     string
+	CreateTimeout     string
+	MaxBodyLength     string
+	MaxTitleLength    string
+	MaxRequestSize    string
+	ConfirmationEmoji string
+
+	// ConfirmationVisibility controls who sees the post confirming that a post was marked for
+	// documentation: everyone in the channel, only the requesting user, or no one.
+	ConfirmationVisibility string
+
+	// ConfirmationTemplate is a Go text/template rendering the message of the post confirming that
+	// a post was marked for documentation, replacing the default message and footer.
+	ConfirmationTemplate string
+
+	// ConfirmationChannelID is the ID of a channel that public confirmations are posted to instead
+	// of the thread of the marked post, such as an admin channel aggregating all requests.
+	ConfirmationChannelID string
+
+	// SubmitCooldownSeconds is how long after a post is marked for documentation that further
+	// requests to mark it are rejected, guarding against double submissions. Zero disables it.
+	SubmitCooldownSeconds string
+
+	// IdempotencyKeyExpirySeconds is how long the results of requests made with an
+	// Idempotency-Key header are kept for replaying to retries. Defaults to a day.
+	IdempotencyKeyExpirySeconds string
+
+	// DeduplicatePosts comments on the issue already created for a post when it is marked for the
+	// same repository again, instead of creating another issue, whatever the title.
+	DeduplicatePosts bool
+
+	// TriggerEmoji is the emoji that files a documentation issue for a post when someone replies
+	// to it with only that emoji. Leave it empty to disable the trigger.
+	TriggerEmoji string
+
+	// BodyCodeLanguage is the language of the code fence the post is wrapped in within the issue
+	// body, such as markdown, for highlighting. WrapBodyInCodeFence can be set to false to include
+	// the post as is instead, which suits prose. The post is wrapped when it is not set.
+	BodyCodeLanguage    string
+	WrapBodyInCodeFence *bool
+
+	// UseOnCallSchedule assigns issues to whoever is on call for the channel of the marked post
+	// according to OnCallSchedule, a JSON object mapping channel IDs to objects mapping days of
+	// the week, such as monday, to a GitHub username. Assignees is used when no one is on call.
+	UseOnCallSchedule bool
+	OnCallSchedule    string
+
+	// AssignToAuthor also assigns issues to the GitHub account of the author of the marked post,
+	// looked up in AssignToAuthorMapping, a JSON object mapping Mattermost usernames to GitHub
+	// usernames, or else from their connected GitHub account. Issues are not assigned to authors
+	// with neither.
+	AssignToAuthor        bool
+	AssignToAuthorMapping string
+
+	// IncludePermalink links the issue body to the marked post and names the Mattermost server it
+	// was posted on. It can be set to false to keep the site URL of private deployments out of
+	// public issues. The permalink is included when it is not set.
+	IncludePermalink *bool
+
+	// TemplatePath is the path of an issue template in the target repository, such as
+	// .github/ISSUE_TEMPLATE/documentation.md, that the rendered body is substituted into.
+	TemplatePath string
+
+	// Milestone is the number or title of the GitHub milestone created issues are added to.
+	// TypeMilestoneMap is a JSON object mapping issue types to a milestone number or title,
+	// taking precedence over Milestone.
+	Milestone        string
+	TypeMilestoneMap string
+
+	// TypeLabelMap is a JSON object mapping issue types to comma separated labels, added to
+	// issues of that type in addition to Labels.
+	TypeLabelMap string
+
+	// TypeBodyTemplateMap is a JSON object mapping issue types to a Go text/template rendering
+	// the body of their issues, taking precedence over BodyTemplate. TypeTitleTemplateMap maps
+	// issue types to a template rendering the title of their issues, in place of the TitlePrefix
+	// followed by the requested title.
+	TypeBodyTemplateMap  string
+	TypeTitleTemplateMap string
+
+	// IssueMarker is the text of the HTML comment appended to created issues, so that they can be
+	// found with GitHub's issue search. Defaults to docup:v1; changing it hides earlier issues from
+	// /docup list, duplicate detection and webhooks.
+	IssueMarker string
+
+	// NotifyTeam is a GitHub team, such as @org/docs-team, mentioned at the end of created issues
+	// so that it is notified of them.
+	NotifyTeam string
+
+	// IssueType is the name of the native GitHub issue type, such as Task, set on created issues.
+	// A label of the same name is added instead in repositories without that issue type.
+	IssueType string
+
+	// LockIssues locks the conversation of created issues to collaborators, giving GitHub
+	// LockReason, if set, as the reason.
+	LockIssues bool
+	LockReason string
+
+	// IdentifierLabel is added to every issue the plugin creates, so that they can be found
+	// reliably. Set it to "none" to add no such label.
+	IdentifierLabel string
+
+	// ProjectID and ProjectColumnID identify the GitHub project board and the column of that
+	// board that created issues are added to as cards.
+	ProjectID       string
+	ProjectColumnID string
+
+	// ValidateReposOnStartup checks on activation that every configured repository is accessible
+	// with the configured credentials, logging a warning for any that are not.
+	ValidateReposOnStartup bool
+
+	// SyncThreadReplies adds replies posted in the thread of a marked post after its issue was
+	// created to the issue as comments.
+	SyncThreadReplies bool
+
+	// SanitizeMentions turns @username mentions and ~channel links in posts into plain text
+	// before they are included in issues.
+	SanitizeMentions bool
+
+	// ConvertEmoji replaces common Mattermost emoji shortcodes in posts with their unicode emoji
+	// before they are included in issues.
+	ConvertEmoji bool
+
+	// IncludeThread adds a transcript of the other posts in the thread of a marked post to its
+	// issue, and MaxThreadMessages caps the number of posts in the transcript.
+	IncludeThread     bool
+	MaxThreadMessages string
+
+	// AllowedUserIDs is a comma separated list of the IDs of users permitted to create issues, and
+	// AllowedRole a role whose members are also permitted to. Everyone is permitted when both are
+	// empty.
+	AllowedUserIDs string
+	AllowedRole    string
+
+	// PreferUserToken creates issues as the requesting user when they have connected their GitHub
+	// account through the OAuth app identified by GitHubOAuthClientID and GitHubOAuthClientSecret.
+	// Their tokens are encrypted with EncryptionKey. The GitHubAPIKey is used otherwise.
+	PreferUserToken         bool
+	GitHubOAuthClientID     string
+	GitHubOAuthClientSecret string
+	EncryptionKey           string
+
+	// WebhookSecret verifies the signature of GitHub webhook payloads.
+	WebhookSecret string
+
+	// BlockedPatterns is a list of regular expressions, one per line, such as the format of API
+	// keys. Requests whose title or body matches any of them are rejected to avoid leaking
+	// secrets. OnConfigurationChange compiles them into blockedPatterns.
+	BlockedPatterns string
+	blockedPatterns []*regexp.Regexp
+
+	// RedactSecrets replaces strings that look like secrets, such as GitHub tokens and AWS keys,
+	// with [REDACTED] in issue bodies rather than rejecting the request. RedactPatterns lists more
+	// regular expressions to redact, one per line, on top of the built-in ones.
+	// OnConfigurationChange compiles both into redactPatterns.
+	RedactSecrets  bool
+	RedactPatterns string
+	redactPatterns []*regexp.Regexp
+
+	// CORSAllowedOrigins is a comma separated list of origins, such as http://localhost:9005,
+	// allowed to make cross-origin requests to create issues, for developing the webapp.
+	CORSAllowedOrigins string
 }
 
+const (
+	// defaultTitlePrefix is prepended to the titles of documentation issues when TitlePrefix is
+	// not configured.
+	defaultTitlePrefix = "Request for Documentation: "
+
+	// noTitlePrefix is the TitlePrefix value used to disable the prefix entirely.
+	noTitlePrefix = "none"
+
+	// defaultIssueFooter and defaultPostFooter end issue bodies and confirmation posts when Footer
+	// is not configured, and noFooter is the Footer value used to remove them entirely.
+	defaultIssueFooter = "_This issue was generated from [Mattermost](https://mattermost.com) using the [Doc Up](https://github.com/jwilander/mattermost-plugin-docup) plugin._"
+	defaultPostFooter  = "_Generated by the [Doc Up](https://github.com/jwilander/mattermost-plugin-docup) plugin._"
+	noFooter           = "none"
+
+	// defaultMaxRetries is the number of attempts made to create an issue when MaxRetries is not
+	// configured, and maxMaxRetries bounds the configured value to keep requests short.
+	defaultMaxRetries = 3
+	maxMaxRetries     = 10
+
+	// defaultGitHubTimeout bounds each GitHub call when GitHubTimeout is not configured.
+	defaultGitHubTimeout = 15 * time.Second
+
+	// defaultCreateTimeout bounds each request to the create endpoint when CreateTimeout is not
+	// configured.
+	defaultCreateTimeout = time.Minute
+
+	// defaultMaxBodyLength is the number of characters of a post included in an issue when
+	// MaxBodyLength is not configured.
+	defaultMaxBodyLength = 10000
+
+	// defaultMaxThreadMessages is the number of posts included in thread transcripts when
+	// MaxThreadMessages is not configured.
+	defaultMaxThreadMessages = 20
+
+	// defaultMaxTitleLength is the number of characters issue titles are truncated to when
+	// MaxTitleLength is not configured, which is the most GitHub accepts.
+	defaultMaxTitleLength = 256
+
+	// defaultMaxRequestSize is the number of bytes accepted in the body of a request to create an
+	// issue when MaxRequestSize is not configured.
+	defaultMaxRequestSize = 1 << 20
+
+	// defaultIdentifierLabel is added to every issue the plugin creates when IdentifierLabel is not
+	// configured, and noIdentifierLabel is the IdentifierLabel value used to add no such label.
+	defaultIdentifierLabel = "docup"
+	noIdentifierLabel      = "none"
+
+	// defaultConfirmationEmoji is the reaction added to marked posts when ConfirmationEmoji is
+	// not configured.
+	defaultConfirmationEmoji = "memo"
+)
+
+const (
+	confirmationPublic    = "public"
+	confirmationEphemeral = "ephemeral"
+	confirmationNone      = "none"
+)
+
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
 // your configuration has reference types.
 func (c *configuration) Clone() *configuration {
@@ -33,21 +307,911 @@ func (c *configuration) Clone() *configuration {
 }
 
 func (c *configuration) IsValid() error {
-	if c.GitHubAPIKey == "" {
-		return errors.New("GitHubAPIKey not configured")
+	switch c.Provider {
+	case "", providerGitHub:
+		switch c.AuthMode {
+		case "", authModeToken:
+			if c.GitHubAPIKey == "" {
+				return errors.New("GitHubAPIKey not configured")
+			}
+		case authModeApp:
+			if _, err := strconv.ParseInt(strings.TrimSpace(c.GitHubAppID), 10, 64); err != nil {
+				return errors.New("GitHubAppID must be the number of a GitHub App")
+			}
+			if _, err := strconv.ParseInt(strings.TrimSpace(c.GitHubAppInstallationID), 10, 64); err != nil {
+				return errors.New("GitHubAppInstallationID must be the number of an installation of the GitHub App")
+			}
+			if _, err := parseAppPrivateKey(c.GitHubAppPrivateKey); err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf("unknown AuthMode %q, expected %q or %q", c.AuthMode, authModeToken, authModeApp)
+		}
+	case providerGitLab:
+		if c.GitLabToken == "" {
+			return errors.New("GitLabToken not configured")
+		}
+		if c.GitLabURL != "" {
+			if _, err := url.ParseRequestURI(c.GitLabURL); err != nil {
+				return errors.Wrap(err, "GitLabURL is not a valid URL")
+			}
+		}
+	case providerGitea:
+		if c.GiteaToken == "" {
+			return errors.New("GiteaToken not configured")
+		}
+		giteaURL, err := url.Parse(c.GiteaURL)
+		if err != nil || (giteaURL.Scheme != "http" && giteaURL.Scheme != "https") || giteaURL.Host == "" {
+			return errors.Errorf("GiteaURL must be the absolute http or https URL of the Gitea instance, got %q", c.GiteaURL)
+		}
+	default:
+		return errors.Errorf("unknown Provider %q, expected %q, %q or %q", c.Provider, providerGitHub, providerGitLab, providerGitea)
+	}
+	if c.GitHubBaseURL != "" {
+		baseURL, err := url.Parse(c.GitHubBaseURL)
+		if err != nil {
+			return errors.Wrap(err, "GitHubBaseURL is not a valid URL")
+		}
+		if (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+			return errors.Errorf("GitHubBaseURL must be an absolute http or https URL, got %q", c.GitHubBaseURL)
+		}
+	}
+	if c.GitHubProxyURL != "" {
+		proxyURL, err := url.Parse(c.GitHubProxyURL)
+		if err != nil {
+			return errors.Wrap(err, "GitHubProxyURL is not a valid URL")
+		}
+		if (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Host == "" {
+			return errors.Errorf("GitHubProxyURL must be an absolute http or https URL, got %q", c.GitHubProxyURL)
+		}
+	}
+	if c.MaxRetries != "" {
+		maxRetries, err := strconv.Atoi(c.MaxRetries)
+		if err != nil || maxRetries < 1 || maxRetries > maxMaxRetries {
+			return errors.Errorf("MaxRetries must be a number between 1 and %d", maxMaxRetries)
+		}
+	}
+	if c.GitHubTimeout != "" {
+		timeout, err := strconv.Atoi(c.GitHubTimeout)
+		if err != nil || timeout < 1 {
+			return errors.New("GitHubTimeout must be a positive number of seconds")
+		}
+	}
+	if c.CreateTimeout != "" {
+		timeout, err := strconv.Atoi(c.CreateTimeout)
+		if err != nil || timeout < 1 {
+			return errors.New("CreateTimeout must be a positive number of seconds")
+		}
+	}
+	if c.MaxBodyLength != "" {
+		maxBodyLength, err := strconv.Atoi(c.MaxBodyLength)
+		if err != nil || maxBodyLength < 1 {
+			return errors.New("MaxBodyLength must be a positive number of characters")
+		}
+	}
+	if c.MaxThreadMessages != "" {
+		maxThreadMessages, err := strconv.Atoi(c.MaxThreadMessages)
+		if err != nil || maxThreadMessages < 1 {
+			return errors.New("MaxThreadMessages must be a positive number of posts")
+		}
+	}
+	if c.MaxTitleLength != "" {
+		maxTitleLength, err := strconv.Atoi(c.MaxTitleLength)
+		if err != nil || maxTitleLength < 1 {
+			return errors.New("MaxTitleLength must be a positive number of characters")
+		}
+	}
+	if c.MaxRequestSize != "" {
+		maxRequestSize, err := strconv.ParseInt(c.MaxRequestSize, 10, 64)
+		if err != nil || maxRequestSize < 1 {
+			return errors.New("MaxRequestSize must be a positive number of bytes")
+		}
+	}
+	if c.SubmitCooldownSeconds != "" {
+		cooldown, err := strconv.Atoi(c.SubmitCooldownSeconds)
+		if err != nil || cooldown < 0 {
+			return errors.New("SubmitCooldownSeconds must be a number of seconds")
+		}
+	}
+	if c.IdempotencyKeyExpirySeconds != "" {
+		expiry, err := strconv.Atoi(c.IdempotencyKeyExpirySeconds)
+		if err != nil || expiry < 1 {
+			return errors.New("IdempotencyKeyExpirySeconds must be a positive number of seconds")
+		}
+	}
+	switch c.ConfirmationVisibility {
+	case "", confirmationPublic, confirmationEphemeral, confirmationNone:
+	default:
+		return errors.Errorf("unknown ConfirmationVisibility %q, expected %q, %q or %q", c.ConfirmationVisibility, confirmationPublic, confirmationEphemeral, confirmationNone)
+	}
+	switch c.Mode {
+	case "", modeIssue:
+	case modePRStub:
+		if !c.isGitHub() {
+			return errors.Errorf("Mode %q is only available with the %q Provider", modePRStub, providerGitHub)
+		}
+	default:
+		return errors.Errorf("unknown Mode %q, expected %q or %q", c.Mode, modeIssue, modePRStub)
+	}
+	if strings.ContainsAny(c.BodyCodeLanguage, "` \t\r\n") {
+		return errors.New("BodyCodeLanguage must be a single word without backticks")
+	}
+	if _, err := c.parseBodyTemplate(""); err != nil {
+		return err
+	}
+	typeBodyTemplates, err := c.parseTypeBodyTemplateMap()
+	if err != nil {
+		return err
+	}
+	for issueType := range typeBodyTemplates {
+		if _, ok := repositorySettings[issueType]; !ok {
+			return errors.Errorf("TypeBodyTemplateMap has unknown documentation type %q", issueType)
+		}
+		if _, err := c.parseBodyTemplate(issueType); err != nil {
+			return err
+		}
+	}
+	typeTitleTemplates, err := c.parseTypeTitleTemplateMap()
+	if err != nil {
+		return err
+	}
+	for issueType := range typeTitleTemplates {
+		if _, ok := repositorySettings[issueType]; !ok {
+			return errors.Errorf("TypeTitleTemplateMap has unknown documentation type %q", issueType)
+		}
+		if _, err := c.parseTitleTemplate(issueType); err != nil {
+			return err
+		}
 	}
-	if c.AdminRepository == "" {
-		return errors.New("AdminRepository not configured")
+	if _, err := c.parseConfirmationTemplate(); err != nil {
+		return err
 	}
-	if c.DeveloperRepository == "" {
-		return errors.New("DeveloperRepository not configured")
+	if c.AdminRepository == "" && c.DeveloperRepository == "" && c.HandbookRepository == "" && c.FeatureRepository == "" && c.getOrganization() == "" {
+		return errors.New("no repositories configured, set at least one of AdminRepository, DeveloperRepository, HandbookRepository, FeatureRepository or Organization")
 	}
-	if c.HandbookRepository == "" {
-		return errors.New("HandbookRepository not configured")
+	if organization := c.getOrganization(); organization != "" && !organizationPattern.MatchString(organization) {
+		return errors.Errorf("Organization %q is not a valid GitHub organization name", organization)
+	}
+	if name := strings.TrimSpace(c.OrganizationRepository); name != "" && !repositoryNamePattern.MatchString(name) {
+		return errors.Errorf("OrganizationRepository %q is not a valid repository name", name)
+	}
+	if c.DefaultType != "" && len(c.getRepositories(c.DefaultType)) == 0 {
+		return errors.Errorf("DefaultType %q is not a configured documentation type", c.DefaultType)
+	}
+	channelRepositories, err := c.parseChannelRepositoryMap()
+	if err != nil {
+		return err
+	}
+	for channelID, ownerAndRepo := range channelRepositories {
+		if _, _, err := splitOwnerAndRepo(ownerAndRepo); err != nil {
+			return errors.Wrapf(err, "ChannelRepositoryMap entry for channel %s is invalid", channelID)
+		}
+	}
+	teamRepositories, err := c.parseTeamRepositoryMap()
+	if err != nil {
+		return err
+	}
+	for teamID, typeRepositories := range teamRepositories {
+		for issueType, ownerAndRepo := range typeRepositories {
+			if _, ok := repositorySettings[issueType]; !ok {
+				return errors.Errorf("TeamRepositoryMap entry for team %s has unknown documentation type %q", teamID, issueType)
+			}
+			if _, _, err := splitOwnerAndRepo(ownerAndRepo); err != nil {
+				return errors.Wrapf(err, "TeamRepositoryMap entry for type %s of team %s is invalid", issueType, teamID)
+			}
+		}
+	}
+	if c.UseOnCallSchedule {
+		schedule, err := c.parseOnCallSchedule()
+		if err != nil {
+			return err
+		}
+		for channelID, days := range schedule {
+			for day, assignee := range days {
+				if _, ok := weekdays[strings.ToLower(day)]; !ok {
+					return errors.Errorf("OnCallSchedule entry for channel %s has unknown day %q", channelID, day)
+				}
+				if strings.TrimSpace(assignee) == "" {
+					return errors.Errorf("OnCallSchedule entry for %s of channel %s has no assignee", day, channelID)
+				}
+			}
+		}
+	}
+	if c.AssignToAuthor {
+		mapping, err := c.parseAssignToAuthorMapping()
+		if err != nil {
+			return err
+		}
+		for username, githubUsername := range mapping {
+			if strings.TrimSpace(githubUsername) == "" {
+				return errors.Errorf("AssignToAuthorMapping entry for %s has no GitHub username", username)
+			}
+		}
+	}
+	if marker := strings.TrimSpace(c.IssueMarker); marker != "" && !issueMarkerPattern.MatchString(marker) {
+		return errors.Errorf("IssueMarker %q may only contain letters, digits, colons, dots, underscores and single dashes", marker)
+	}
+	if team := strings.TrimSpace(c.NotifyTeam); team != "" && !notifyTeamPattern.MatchString(team) {
+		return errors.Errorf("NotifyTeam %q must be a GitHub team in @org/team form", team)
+	}
+	switch c.LockReason {
+	case "", "off-topic", "too heated", "resolved", "spam":
+	default:
+		return errors.Errorf("unknown LockReason %q, expected off-topic, too heated, resolved or spam", c.LockReason)
+	}
+	if _, err := c.compileBlockedPatterns(); err != nil {
+		return err
+	}
+	if _, err := c.compileRedactPatterns(); err != nil {
+		return err
+	}
+	for _, origin := range c.getCORSAllowedOrigins() {
+		if err := validateCORSOrigin(origin); err != nil {
+			return err
+		}
+	}
+	if _, err := c.parseTypeMilestoneMap(); err != nil {
+		return err
+	}
+	if _, err := c.parseTypeLabelMap(); err != nil {
+		return err
+	}
+	if (c.ProjectID == "") != (c.ProjectColumnID == "") {
+		return errors.New("ProjectID and ProjectColumnID must be configured together")
+	}
+	if c.ProjectID != "" {
+		if _, err := strconv.ParseInt(c.ProjectID, 10, 64); err != nil {
+			return errors.New("ProjectID must be a number")
+		}
+		if _, err := strconv.ParseInt(c.ProjectColumnID, 10, 64); err != nil {
+			return errors.New("ProjectColumnID must be a number")
+		}
+	}
+	for name, value := range map[string]string{
+		"AdminRepository":     c.AdminRepository,
+		"DeveloperRepository": c.DeveloperRepository,
+		"HandbookRepository":  c.HandbookRepository,
+		"FeatureRepository":   c.FeatureRepository,
+	} {
+		for _, ownerAndRepo := range parseRepositories(value) {
+			if _, _, err := splitOwnerAndRepo(ownerAndRepo); err != nil {
+				return errors.Wrapf(err, "%s is invalid", name)
+			}
+		}
 	}
 	return nil
 }
 
+// isGitHub reports whether issues are filed on GitHub, which features relying on GitHub's API
+// beyond creating issues require.
+func (c *configuration) isGitHub() bool {
+	return c.Provider == "" || c.Provider == providerGitHub
+}
+
+// getGitHubCredentials returns the settings the shared GitHub client authenticates with, so that
+// it can be rebuilt when they change.
+func (c *configuration) getGitHubCredentials() string {
+	if c.AuthMode == authModeApp {
+		return strings.Join([]string{authModeApp, c.GitHubAppID, c.GitHubAppInstallationID, c.GitHubAppPrivateKey}, "\n")
+	}
+	return c.GitHubAPIKey
+}
+
+// isOAuthConfigured reports whether users can connect their GitHub accounts.
+func (c *configuration) isOAuthConfigured() bool {
+	return c.GitHubOAuthClientID != "" && c.GitHubOAuthClientSecret != "" && c.EncryptionKey != ""
+}
+
+// canConnectGitHub reports whether users can connect their GitHub accounts with /docup connect,
+// which assigning issues to them requires whether or not issues are filed as them.
+func (c *configuration) canConnectGitHub() bool {
+	return c.isGitHub() && c.isOAuthConfigured()
+}
+
+// isUserAllowed reports whether the given user is permitted to create issues.
+func (c *configuration) isUserAllowed(user *model.User) bool {
+	allowedUserIDs := strings.TrimSpace(c.AllowedUserIDs)
+	allowedRole := strings.TrimSpace(c.AllowedRole)
+	if allowedUserIDs == "" && allowedRole == "" {
+		return true
+	}
+
+	if allowedUserIDs != "" {
+		for _, userID := range strings.Split(allowedUserIDs, ",") {
+			if strings.TrimSpace(userID) == user.Id {
+				return true
+			}
+		}
+	}
+
+	return allowedRole != "" && user.IsInRole(allowedRole)
+}
+
+// isAssignable reports whether the confirmations of issues of the given type offer an "Assign to
+// me" button, which requires users to connect their GitHub accounts.
+func (c *configuration) isAssignable(issueType string) bool {
+	return issueType == "developer" && c.canConnectGitHub()
+}
+
+// wrapBodyInCodeFence reports whether the post is wrapped in a code fence within issue bodies.
+func (c *configuration) wrapBodyInCodeFence() bool {
+	return c.WrapBodyInCodeFence == nil || *c.WrapBodyInCodeFence
+}
+
+// includePermalink reports whether issue bodies link to the marked post.
+func (c *configuration) includePermalink() bool {
+	return c.IncludePermalink == nil || *c.IncludePermalink
+}
+
+// getTitlePrefix returns the prefix to prepend to the titles of issues of the given type.
+func (c *configuration) getTitlePrefix(issueType string) string {
+	prefix := issueTitlePrefixes[issueType]
+	if prefix != defaultTitlePrefix {
+		return prefix
+	}
+
+	switch strings.TrimSpace(c.TitlePrefix) {
+	case "":
+		return defaultTitlePrefix
+	case noTitlePrefix:
+		return ""
+	default:
+		return strings.TrimSpace(c.TitlePrefix) + " "
+	}
+}
+
+// getIssueFooter returns the text ending the body of created issues, which may be empty.
+func (c *configuration) getIssueFooter() string {
+	return c.getFooter(defaultIssueFooter)
+}
+
+// getPostFooter returns the text ending confirmation posts, which may be empty.
+func (c *configuration) getPostFooter() string {
+	return c.getFooter(defaultPostFooter)
+}
+
+func (c *configuration) getFooter(defaultFooter string) string {
+	switch strings.TrimSpace(c.Footer) {
+	case "":
+		return defaultFooter
+	case noFooter:
+		return ""
+	default:
+		return strings.TrimSpace(c.Footer)
+	}
+}
+
+// getMaxRetries returns the maximum number of attempts made to create an issue.
+func (c *configuration) getMaxRetries() int {
+	maxRetries, err := strconv.Atoi(c.MaxRetries)
+	if err != nil || maxRetries < 1 {
+		return defaultMaxRetries
+	}
+	if maxRetries > maxMaxRetries {
+		return maxMaxRetries
+	}
+	return maxRetries
+}
+
+// getGitHubTimeout returns how long to wait for each GitHub call.
+func (c *configuration) getGitHubTimeout() time.Duration {
+	timeout, err := strconv.Atoi(c.GitHubTimeout)
+	if err != nil || timeout < 1 {
+		return defaultGitHubTimeout
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// getCreateTimeout returns how long to spend on a request to the create endpoint as a whole.
+func (c *configuration) getCreateTimeout() time.Duration {
+	timeout, err := strconv.Atoi(c.CreateTimeout)
+	if err != nil || timeout < 1 {
+		return defaultCreateTimeout
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// getMaxBodyLength returns the number of characters of a post included in an issue.
+func (c *configuration) getMaxBodyLength() int {
+	maxBodyLength, err := strconv.Atoi(c.MaxBodyLength)
+	if err != nil || maxBodyLength < 1 {
+		return defaultMaxBodyLength
+	}
+	return maxBodyLength
+}
+
+// getMaxThreadMessages returns the number of posts included in thread transcripts.
+func (c *configuration) getMaxThreadMessages() int {
+	maxThreadMessages, err := strconv.Atoi(c.MaxThreadMessages)
+	if err != nil || maxThreadMessages < 1 {
+		return defaultMaxThreadMessages
+	}
+	return maxThreadMessages
+}
+
+// getMaxTitleLength returns the number of characters issue titles are truncated to.
+func (c *configuration) getMaxTitleLength() int {
+	maxTitleLength, err := strconv.Atoi(c.MaxTitleLength)
+	if err != nil || maxTitleLength < 1 {
+		return defaultMaxTitleLength
+	}
+	return maxTitleLength
+}
+
+// getMaxRequestSize returns the number of bytes accepted in the body of a request to create an
+// issue.
+func (c *configuration) getMaxRequestSize() int64 {
+	maxRequestSize, err := strconv.ParseInt(c.MaxRequestSize, 10, 64)
+	if err != nil || maxRequestSize < 1 {
+		return defaultMaxRequestSize
+	}
+	return maxRequestSize
+}
+
+// getConfirmationEmoji returns the name of the emoji reaction added to marked posts.
+func (c *configuration) getConfirmationEmoji() string {
+	emoji := strings.Trim(strings.TrimSpace(c.ConfirmationEmoji), ":")
+	if emoji == "" {
+		return defaultConfirmationEmoji
+	}
+	return emoji
+}
+
+// getSubmitCooldownSeconds returns how many seconds after a post is marked for documentation that
+// further requests to mark it are rejected, or 0 if they are not.
+func (c *configuration) getSubmitCooldownSeconds() int64 {
+	cooldown, err := strconv.ParseInt(c.SubmitCooldownSeconds, 10, 64)
+	if err != nil || cooldown < 0 {
+		return 0
+	}
+	return cooldown
+}
+
+// getIdempotencyKeyExpirySeconds returns how many seconds the results of requests made with an
+// idempotency key are kept for.
+func (c *configuration) getIdempotencyKeyExpirySeconds() int64 {
+	expiry, err := strconv.ParseInt(c.IdempotencyKeyExpirySeconds, 10, 64)
+	if err != nil || expiry < 1 {
+		return defaultIdempotencyKeyExpirySeconds
+	}
+	return expiry
+}
+
+// getTriggerEmoji returns the name of the emoji that files a documentation issue for the post it
+// replies to, or an empty string if the trigger is disabled.
+func (c *configuration) getTriggerEmoji() string {
+	return strings.Trim(strings.TrimSpace(c.TriggerEmoji), ":")
+}
+
+// getConfirmationVisibility returns who sees the post confirming that a post was marked for
+// documentation.
+func (c *configuration) getConfirmationVisibility() string {
+	if c.ConfirmationVisibility == "" {
+		return confirmationPublic
+	}
+	return c.ConfirmationVisibility
+}
+
+// getMode returns whether posts marked for documentation are filed as issues or as pull requests
+// adding a placeholder document.
+func (c *configuration) getMode() string {
+	if c.Mode == "" {
+		return modeIssue
+	}
+	return c.Mode
+}
+
+// getDocsDirectory returns the directory of the repository placeholder documents are added to.
+func (c *configuration) getDocsDirectory() string {
+	directory := strings.Trim(strings.TrimSpace(c.DocsDirectory), "/")
+	if directory == "" {
+		return defaultDocsDirectory
+	}
+	return directory
+}
+
+// repositorySettings maps issue types to the setting holding their repositories.
+var repositorySettings = map[string]string{
+	"admin":     "AdminRepository",
+	"developer": "DeveloperRepository",
+	"handbook":  "HandbookRepository",
+	"feature":   "FeatureRepository",
+}
+
+// getRepositories returns the owner/repo entries configured for the given issue type, the first
+// of which is the default. Types with no repository configured fall back to the
+// OrganizationRepository of the Organization, if any.
+func (c *configuration) getRepositories(issueType string) []string {
+	if _, ok := repositorySettings[issueType]; !ok {
+		return []string{}
+	}
+	if repositories := c.getConfiguredRepositories(issueType); len(repositories) > 0 {
+		return repositories
+	}
+	if organization := c.getOrganization(); organization != "" {
+		return []string{organization + "/" + c.getOrganizationRepository()}
+	}
+	return []string{}
+}
+
+// getConfiguredRepositories returns the owner/repo entries of the setting of the given issue type.
+func (c *configuration) getConfiguredRepositories(issueType string) []string {
+	switch issueType {
+	case "admin":
+		return parseRepositories(c.AdminRepository)
+	case "developer":
+		return parseRepositories(c.DeveloperRepository)
+	case "handbook":
+		return parseRepositories(c.HandbookRepository)
+	case "feature":
+		return parseRepositories(c.FeatureRepository)
+	}
+	return []string{}
+}
+
+var (
+	// organizationPattern matches GitHub organization names.
+	organizationPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?$`)
+
+	// repositoryNamePattern matches GitHub repository names, without their owner.
+	repositoryNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// defaultOrganizationRepository is the repository of the Organization issues are filed in when
+// OrganizationRepository is not configured.
+const defaultOrganizationRepository = "documentation"
+
+// getOrganization returns the configured GitHub organization, or an empty string if there is
+// none.
+func (c *configuration) getOrganization() string {
+	return strings.TrimSpace(c.Organization)
+}
+
+// getOrganizationRepository returns the name of the repository of the Organization issues are
+// filed in by default.
+func (c *configuration) getOrganizationRepository() string {
+	if name := strings.TrimSpace(c.OrganizationRepository); name != "" {
+		return name
+	}
+	return defaultOrganizationRepository
+}
+
+// resolveOrganizationRepository returns the owner/repo of the repository of the Organization
+// requested for the given issue type, named with or without the organization. An empty string is
+// returned if the type has repositories configured, there is no Organization, or the repository
+// is not one of its.
+func (c *configuration) resolveOrganizationRepository(issueType, repository string) string {
+	organization := c.getOrganization()
+	if organization == "" || len(c.getConfiguredRepositories(issueType)) > 0 {
+		return ""
+	}
+
+	name := repository
+	if owner, repo, err := splitOwnerAndRepo(repository); err == nil {
+		if !strings.EqualFold(owner, organization) {
+			return ""
+		}
+		name = repo
+	}
+	if !repositoryNamePattern.MatchString(name) {
+		return ""
+	}
+	return organization + "/" + name
+}
+
+// parseChannelRepositoryMap decodes ChannelRepositoryMap.
+func (c *configuration) parseChannelRepositoryMap() (map[string]string, error) {
+	channelRepositories := map[string]string{}
+	if strings.TrimSpace(c.ChannelRepositoryMap) == "" {
+		return channelRepositories, nil
+	}
+
+	if err := json.Unmarshal([]byte(c.ChannelRepositoryMap), &channelRepositories); err != nil {
+		return nil, errors.Wrap(err, "ChannelRepositoryMap must be a JSON object mapping channel IDs to owner/repo")
+	}
+	return channelRepositories, nil
+}
+
+// getChannelRepository returns the owner/repo configured for the given channel, or an empty
+// string if the channel has no mapping.
+func (c *configuration) getChannelRepository(channelID string) string {
+	channelRepositories, err := c.parseChannelRepositoryMap()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(channelRepositories[channelID])
+}
+
+// parseTeamRepositoryMap decodes TeamRepositoryMap.
+func (c *configuration) parseTeamRepositoryMap() (map[string]map[string]string, error) {
+	teamRepositories := map[string]map[string]string{}
+	if strings.TrimSpace(c.TeamRepositoryMap) == "" {
+		return teamRepositories, nil
+	}
+
+	if err := json.Unmarshal([]byte(c.TeamRepositoryMap), &teamRepositories); err != nil {
+		return nil, errors.Wrap(err, "TeamRepositoryMap must be a JSON object mapping team IDs to objects mapping documentation types to owner/repo")
+	}
+	return teamRepositories, nil
+}
+
+// getTeamRepository returns the owner/repo configured for issues of the given type in the given
+// team, or an empty string if the team has no mapping for the type.
+func (c *configuration) getTeamRepository(teamID, issueType string) string {
+	teamRepositories, err := c.parseTeamRepositoryMap()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(teamRepositories[teamID][issueType])
+}
+
+// weekdays maps the lowercased names of the days of the week to the days.
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseOnCallSchedule decodes OnCallSchedule.
+func (c *configuration) parseOnCallSchedule() (map[string]map[string]string, error) {
+	schedule := map[string]map[string]string{}
+	if strings.TrimSpace(c.OnCallSchedule) == "" {
+		return schedule, nil
+	}
+
+	if err := json.Unmarshal([]byte(c.OnCallSchedule), &schedule); err != nil {
+		return nil, errors.Wrap(err, "OnCallSchedule must be a JSON object mapping channel IDs to objects mapping days of the week to GitHub usernames")
+	}
+	return schedule, nil
+}
+
+// getOnCallAssignee returns the GitHub username on call for the given channel at the given time,
+// or an empty string if the on-call schedule is disabled or has no one on call then.
+func (c *configuration) getOnCallAssignee(channelID string, now time.Time) string {
+	if !c.UseOnCallSchedule {
+		return ""
+	}
+
+	schedule, err := c.parseOnCallSchedule()
+	if err != nil {
+		return ""
+	}
+	for day, assignee := range schedule[channelID] {
+		if weekday, ok := weekdays[strings.ToLower(day)]; ok && weekday == now.Weekday() {
+			return strings.TrimSpace(assignee)
+		}
+	}
+	return ""
+}
+
+// parseAssignToAuthorMapping decodes AssignToAuthorMapping.
+func (c *configuration) parseAssignToAuthorMapping() (map[string]string, error) {
+	mapping := map[string]string{}
+	if strings.TrimSpace(c.AssignToAuthorMapping) == "" {
+		return mapping, nil
+	}
+
+	if err := json.Unmarshal([]byte(c.AssignToAuthorMapping), &mapping); err != nil {
+		return nil, errors.Wrap(err, "AssignToAuthorMapping must be a JSON object mapping Mattermost usernames to GitHub usernames")
+	}
+	return mapping, nil
+}
+
+// getMappedGitHubUsername returns the GitHub username AssignToAuthorMapping maps the given
+// Mattermost username to, or an empty string if it has none.
+func (c *configuration) getMappedGitHubUsername(username string) string {
+	mapping, err := c.parseAssignToAuthorMapping()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(mapping[username])
+}
+
+// notifyTeamPattern loosely matches GitHub team mentions, such as @org/docs-team.
+var notifyTeamPattern = regexp.MustCompile(`^@[A-Za-z0-9-]+/[A-Za-z0-9._-]+$`)
+
+// compileBlockedPatterns compiles BlockedPatterns, skipping blank lines.
+func (c *configuration) compileBlockedPatterns() ([]*regexp.Regexp, error) {
+	patterns := []*regexp.Regexp{}
+	for _, line := range strings.Split(c.BlockedPatterns, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "BlockedPatterns entry %q is not a valid regular expression", line)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// isBlocked reports whether any of the given texts matches one of the compiled BlockedPatterns.
+func (c *configuration) isBlocked(texts ...string) bool {
+	for _, pattern := range c.blockedPatterns {
+		for _, text := range texts {
+			if pattern.MatchString(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseTypeMilestoneMap decodes TypeMilestoneMap.
+func (c *configuration) parseTypeMilestoneMap() (map[string]string, error) {
+	typeMilestones := map[string]string{}
+	if strings.TrimSpace(c.TypeMilestoneMap) == "" {
+		return typeMilestones, nil
+	}
+
+	if err := json.Unmarshal([]byte(c.TypeMilestoneMap), &typeMilestones); err != nil {
+		return nil, errors.Wrap(err, "TypeMilestoneMap must be a JSON object mapping issue types to milestones")
+	}
+	return typeMilestones, nil
+}
+
+// getMilestone returns the number or title of the milestone for issues of the given type, or an
+// empty string if they are not added to a milestone.
+func (c *configuration) getMilestone(issueType string) string {
+	typeMilestones, err := c.parseTypeMilestoneMap()
+	if err == nil {
+		if milestone := strings.TrimSpace(typeMilestones[issueType]); milestone != "" {
+			return milestone
+		}
+	}
+	return strings.TrimSpace(c.Milestone)
+}
+
+// parseTypeLabelMap decodes TypeLabelMap.
+func (c *configuration) parseTypeLabelMap() (map[string]string, error) {
+	typeLabels := map[string]string{}
+	if strings.TrimSpace(c.TypeLabelMap) == "" {
+		return typeLabels, nil
+	}
+
+	if err := json.Unmarshal([]byte(c.TypeLabelMap), &typeLabels); err != nil {
+		return nil, errors.Wrap(err, "TypeLabelMap must be a JSON object mapping issue types to comma separated labels")
+	}
+	return typeLabels, nil
+}
+
+// parseTypeBodyTemplateMap decodes TypeBodyTemplateMap.
+func (c *configuration) parseTypeBodyTemplateMap() (map[string]string, error) {
+	typeBodyTemplates := map[string]string{}
+	if strings.TrimSpace(c.TypeBodyTemplateMap) == "" {
+		return typeBodyTemplates, nil
+	}
+
+	if err := json.Unmarshal([]byte(c.TypeBodyTemplateMap), &typeBodyTemplates); err != nil {
+		return nil, errors.Wrap(err, "TypeBodyTemplateMap must be a JSON object mapping issue types to body templates")
+	}
+	return typeBodyTemplates, nil
+}
+
+// parseTypeTitleTemplateMap decodes TypeTitleTemplateMap.
+func (c *configuration) parseTypeTitleTemplateMap() (map[string]string, error) {
+	typeTitleTemplates := map[string]string{}
+	if strings.TrimSpace(c.TypeTitleTemplateMap) == "" {
+		return typeTitleTemplates, nil
+	}
+
+	if err := json.Unmarshal([]byte(c.TypeTitleTemplateMap), &typeTitleTemplates); err != nil {
+		return nil, errors.Wrap(err, "TypeTitleTemplateMap must be a JSON object mapping issue types to title templates")
+	}
+	return typeTitleTemplates, nil
+}
+
+// getLabels returns the configured labels for issues of the given type, merging the labels for
+// all issues with those for the type.
+func (c *configuration) getLabels(issueType string) []string {
+	typeLabels, err := c.parseTypeLabelMap()
+	if err != nil {
+		typeLabels = map[string]string{}
+	}
+	return mergeLabels(parseLabels(c.Labels), parseLabels(typeLabels[issueType]))
+}
+
+// getIdentifierLabel returns the label added to every issue the plugin creates, or an empty string
+// if no such label is added.
+func (c *configuration) getIdentifierLabel() string {
+	switch label := strings.TrimSpace(c.IdentifierLabel); label {
+	case "":
+		return defaultIdentifierLabel
+	case noIdentifierLabel:
+		return ""
+	default:
+		return label
+	}
+}
+
+// parseLabels splits a comma separated list of labels, trimming each label and dropping empty
+// entries, which GitHub rejects.
+func parseLabels(labels string) []string {
+	split := []string{}
+	for _, label := range strings.Split(labels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			split = append(split, label)
+		}
+	}
+	return split
+}
+
+// getProjectColumnID returns the ID of the project column created issues are added to, and
+// whether one is configured.
+func (c *configuration) getProjectColumnID() (int64, bool) {
+	if c.ProjectID == "" {
+		return 0, false
+	}
+	columnID, err := strconv.ParseInt(c.ProjectColumnID, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return columnID, true
+}
+
+// getEnabledTypes returns the issue types that have at least one repository configured.
+func (c *configuration) getEnabledTypes() []string {
+	enabledTypes := []string{}
+	for _, issueType := range []string{"admin", "developer", "handbook", "feature"} {
+		if len(c.getRepositories(issueType)) > 0 {
+			enabledTypes = append(enabledTypes, issueType)
+		}
+	}
+	return enabledTypes
+}
+
+// getAllRepositories returns every distinct owner/repo entry configured for an issue type or a
+// channel.
+func (c *configuration) getAllRepositories() []string {
+	all := []string{}
+	seen := map[string]bool{}
+	add := func(ownerAndRepo string) {
+		key := strings.ToLower(ownerAndRepo)
+		if ownerAndRepo == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		all = append(all, ownerAndRepo)
+	}
+
+	for _, issueType := range []string{"admin", "developer", "handbook", "feature"} {
+		for _, ownerAndRepo := range c.getRepositories(issueType) {
+			add(ownerAndRepo)
+		}
+	}
+	channelRepositories, _ := c.parseChannelRepositoryMap()
+	for _, ownerAndRepo := range channelRepositories {
+		add(strings.TrimSpace(ownerAndRepo))
+	}
+	return all
+}
+
+// parseRepositories splits a repository setting into its comma or newline separated
+// owner/repo entries, ignoring surrounding whitespace and empty entries.
+func parseRepositories(value string) []string {
+	repositories := []string{}
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			repositories = append(repositories, entry)
+		}
+	}
+	return repositories
+}
+
+// splitOwnerAndRepo splits an owner/repo entry into its two parts.
+func splitOwnerAndRepo(ownerAndRepo string) (string, string, error) {
+	repoSplit := strings.Split(ownerAndRepo, "/")
+	if len(repoSplit) != 2 || repoSplit[0] == "" || repoSplit[1] == "" {
+		return "", "", errors.Errorf("repository %q is not in owner/repo form", ownerAndRepo)
+	}
+	return repoSplit[0], repoSplit[1], nil
+}
+
 // getConfiguration retrieves the active configuration under lock, making it safe to use
 // concurrently. The active configuration may change underneath the client of this method, but
 // the struct returned by this API call is considered immutable.
@@ -98,7 +1262,23 @@ func (p *Plugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "failed to load plugin configuration")
 	}
 
+	blockedPatterns, err := configuration.compileBlockedPatterns()
+	if err != nil {
+		return err
+	}
+	configuration.blockedPatterns = blockedPatterns
+
+	redactPatterns, err := configuration.compileRedactPatterns()
+	if err != nil {
+		return err
+	}
+	configuration.redactPatterns = redactPatterns
+
 	p.setConfiguration(configuration)
 
+	if err := p.ensureGitHubClient(configuration); err != nil {
+		return errors.Wrap(err, "failed to rebuild GitHub client")
+	}
+
 	return nil
 }
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTitlePrefix(t *testing.T) {
+	for name, tc := range map[string]struct {
+		TitlePrefix string
+		Type        string
+		Expected    string
+	}{
+		"default prefix": {
+			TitlePrefix: "",
+			Type:        "admin",
+			Expected:    "Request for Documentation: ",
+		},
+		"custom prefix": {
+			TitlePrefix: "Docs:",
+			Type:        "developer",
+			Expected:    "Docs: ",
+		},
+		"no prefix": {
+			TitlePrefix: "none",
+			Type:        "handbook",
+			Expected:    "",
+		},
+		"feature prefix is not overridden": {
+			TitlePrefix: "Docs:",
+			Type:        "feature",
+			Expected:    "Feature Request: ",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{TitlePrefix: tc.TitlePrefix}
+			assert.Equal(t, tc.Expected, config.getTitlePrefix(tc.Type))
+		})
+	}
+}
+
+func TestGetMilestone(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Milestone        string
+		TypeMilestoneMap string
+		Type             string
+		Expected         string
+	}{
+		"no milestone": {
+			Type:     "admin",
+			Expected: "",
+		},
+		"milestone for all types": {
+			Milestone: "Docs Backlog",
+			Type:      "admin",
+			Expected:  "Docs Backlog",
+		},
+		"milestone for type": {
+			Milestone:        "Docs Backlog",
+			TypeMilestoneMap: `{"feature": "7"}`,
+			Type:             "feature",
+			Expected:         "7",
+		},
+		"milestone for other type": {
+			Milestone:        "Docs Backlog",
+			TypeMilestoneMap: `{"feature": "7"}`,
+			Type:             "developer",
+			Expected:         "Docs Backlog",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{Milestone: tc.Milestone, TypeMilestoneMap: tc.TypeMilestoneMap}
+			assert.Equal(t, tc.Expected, config.getMilestone(tc.Type))
+		})
+	}
+}
+
+func TestGetLabels(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Labels       string
+		TypeLabelMap string
+		Type         string
+		Expected     []string
+	}{
+		"no labels": {
+			Type:     "admin",
+			Expected: []string{},
+		},
+		"labels for all types": {
+			Labels:   "documentation, triage",
+			Type:     "admin",
+			Expected: []string{"documentation", "triage"},
+		},
+		"labels for type": {
+			Labels:       "documentation",
+			TypeLabelMap: `{"admin": "admin", "developer": "dev"}`,
+			Type:         "developer",
+			Expected:     []string{"documentation", "dev"},
+		},
+		"duplicate labels": {
+			Labels:       "documentation",
+			TypeLabelMap: `{"admin": "Documentation,admin"}`,
+			Type:         "admin",
+			Expected:     []string{"documentation", "admin"},
+		},
+		"no labels for type": {
+			Labels:       "documentation",
+			TypeLabelMap: `{"admin": "admin"}`,
+			Type:         "handbook",
+			Expected:     []string{"documentation"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{Labels: tc.Labels, TypeLabelMap: tc.TypeLabelMap}
+			assert.Equal(t, tc.Expected, config.getLabels(tc.Type))
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	for labels, expected := range map[string][]string{
+		"":                      {},
+		" , ,":                  {},
+		"documentation":         {"documentation"},
+		"a, b, ,c,":             {"a", "b", "c"},
+		"  needs docs ,triage ": {"needs docs", "triage"},
+	} {
+		assert.Equal(t, expected, parseLabels(labels), labels)
+	}
+}
+
+func TestGetRepositoriesOrganization(t *testing.T) {
+	config := &configuration{AdminRepository: "owner/admin", Organization: "my-org"}
+	assert.Equal(t, []string{"owner/admin"}, config.getRepositories("admin"))
+	assert.Equal(t, []string{"my-org/documentation"}, config.getRepositories("developer"))
+	assert.Empty(t, config.getRepositories("sales"))
+
+	config.OrganizationRepository = "docs"
+	assert.Equal(t, []string{"my-org/docs"}, config.getRepositories("handbook"))
+
+	for repository, expected := range map[string]string{
+		"guides":          "my-org/guides",
+		"My-Org/guides":   "my-org/guides",
+		"other-org/guide": "",
+		"bad/name/guides": "",
+	} {
+		assert.Equal(t, expected, config.resolveOrganizationRepository("developer", repository), repository)
+	}
+	assert.Empty(t, config.resolveOrganizationRepository("admin", "guides"), "types with repositories configured should not accept organization repositories")
+}
+
+func TestGetOnCallAssignee(t *testing.T) {
+	schedule := `{"channel1": {"monday": "alice", "Tuesday": " bob "}}`
+	monday := time.Date(2019, time.June, 3, 9, 0, 0, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		UseOnCallSchedule bool
+		ChannelID         string
+		Now               time.Time
+		Expected          string
+	}{
+		"on call":           {UseOnCallSchedule: true, ChannelID: "channel1", Now: monday, Expected: "alice"},
+		"capitalized day":   {UseOnCallSchedule: true, ChannelID: "channel1", Now: monday.AddDate(0, 0, 1), Expected: "bob"},
+		"no one on call":    {UseOnCallSchedule: true, ChannelID: "channel1", Now: monday.AddDate(0, 0, 2)},
+		"channel not in it": {UseOnCallSchedule: true, ChannelID: "channel2", Now: monday},
+		"disabled":          {ChannelID: "channel1", Now: monday},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{UseOnCallSchedule: tc.UseOnCallSchedule, OnCallSchedule: schedule}
+			assert.Equal(t, tc.Expected, config.getOnCallAssignee(tc.ChannelID, tc.Now))
+		})
+	}
+}
+
+func TestGetFooter(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Footer              string
+		ExpectedIssueFooter string
+		ExpectedPostFooter  string
+	}{
+		"default footer": {
+			Footer:              "",
+			ExpectedIssueFooter: defaultIssueFooter,
+			ExpectedPostFooter:  defaultPostFooter,
+		},
+		"custom footer": {
+			Footer:              " _Filed by the docs team._ ",
+			ExpectedIssueFooter: "_Filed by the docs team._",
+			ExpectedPostFooter:  "_Filed by the docs team._",
+		},
+		"no footer": {
+			Footer:              "none",
+			ExpectedIssueFooter: "",
+			ExpectedPostFooter:  "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{Footer: tc.Footer}
+			assert.Equal(t, tc.ExpectedIssueFooter, config.getIssueFooter())
+			assert.Equal(t, tc.ExpectedPostFooter, config.getPostFooter())
+		})
+	}
+}
+
+func TestIsUserAllowed(t *testing.T) {
+	for name, tc := range map[string]struct {
+		AllowedUserIDs string
+		AllowedRole    string
+		User           *model.User
+		Expected       bool
+	}{
+		"everyone allowed by default": {
+			User:     &model.User{Id: "user1", Roles: model.SYSTEM_USER_ROLE_ID},
+			Expected: true,
+		},
+		"listed user": {
+			AllowedUserIDs: "user2, user1",
+			User:           &model.User{Id: "user1", Roles: model.SYSTEM_USER_ROLE_ID},
+			Expected:       true,
+		},
+		"unlisted user": {
+			AllowedUserIDs: "user2",
+			User:           &model.User{Id: "user1", Roles: model.SYSTEM_USER_ROLE_ID},
+			Expected:       false,
+		},
+		"user with role": {
+			AllowedRole: model.SYSTEM_ADMIN_ROLE_ID,
+			User:        &model.User{Id: "user1", Roles: model.SYSTEM_USER_ROLE_ID + " " + model.SYSTEM_ADMIN_ROLE_ID},
+			Expected:    true,
+		},
+		"user without role": {
+			AllowedRole: model.SYSTEM_ADMIN_ROLE_ID,
+			User:        &model.User{Id: "user1", Roles: model.SYSTEM_USER_ROLE_ID},
+			Expected:    false,
+		},
+		"unlisted user with role": {
+			AllowedUserIDs: "user2",
+			AllowedRole:    model.SYSTEM_ADMIN_ROLE_ID,
+			User:           &model.User{Id: "user1", Roles: model.SYSTEM_ADMIN_ROLE_ID},
+			Expected:       true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{AllowedUserIDs: tc.AllowedUserIDs, AllowedRole: tc.AllowedRole}
+			assert.Equal(t, tc.Expected, config.isUserAllowed(tc.User))
+		})
+	}
+}
+
+func TestGetIdentifierLabel(t *testing.T) {
+	for name, tc := range map[string]struct {
+		IdentifierLabel string
+		Expected        string
+	}{
+		"default label": {IdentifierLabel: "", Expected: "docup"},
+		"custom label":  {IdentifierLabel: " from-mattermost ", Expected: "from-mattermost"},
+		"no label":      {IdentifierLabel: "none", Expected: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{IdentifierLabel: tc.IdentifierLabel}
+			assert.Equal(t, tc.Expected, config.getIdentifierLabel())
+		})
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	_, testAppPrivateKey := newTestAppPrivateKey(t)
+
+	for name, tc := range map[string]struct {
+		Configuration *configuration
+		ExpectedError string
+	}{
+		"valid": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", DeveloperRepository: "owner/developer", HandbookRepository: "owner/handbook"},
+		},
+		"only one repository": {
+			Configuration: &configuration{GitHubAPIKey: "key", HandbookRepository: "owner/handbook"},
+		},
+		"empty GitHubAPIKey": {
+			Configuration: &configuration{AdminRepository: "owner/admin"},
+			ExpectedError: "GitHubAPIKey not configured",
+		},
+		"GitHub App": {
+			Configuration: &configuration{AuthMode: "app", GitHubAppID: "12", GitHubAppInstallationID: "34", GitHubAppPrivateKey: testAppPrivateKey, AdminRepository: "owner/admin"},
+		},
+		"GitHub App without installation": {
+			Configuration: &configuration{AuthMode: "app", GitHubAppID: "12", GitHubAppPrivateKey: testAppPrivateKey, AdminRepository: "owner/admin"},
+			ExpectedError: "GitHubAppInstallationID must be the number of an installation of the GitHub App",
+		},
+		"GitHub App with invalid private key": {
+			Configuration: &configuration{AuthMode: "app", GitHubAppID: "12", GitHubAppInstallationID: "34", GitHubAppPrivateKey: "key", AdminRepository: "owner/admin"},
+			ExpectedError: "GitHubAppPrivateKey must be a PEM encoded private key",
+		},
+		"unknown AuthMode": {
+			Configuration: &configuration{AuthMode: "password", AdminRepository: "owner/admin"},
+			ExpectedError: `unknown AuthMode "password", expected "token" or "app"`,
+		},
+		"no repositories": {
+			Configuration: &configuration{GitHubAPIKey: "key"},
+			ExpectedError: "no repositories configured, set at least one of AdminRepository, DeveloperRepository, HandbookRepository, FeatureRepository or Organization",
+		},
+		"feature repository only": {
+			Configuration: &configuration{GitHubAPIKey: "key", FeatureRepository: "owner/feature"},
+		},
+		"invalid ConfirmationTemplate": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", ConfirmationTemplate: "Filed {{.IssueURL"},
+			ExpectedError: "failed to parse ConfirmationTemplate: template: confirmation:1: unclosed action",
+		},
+		"team repositories": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", TeamRepositoryMap: `{"team1": {"admin": "team/admin", "handbook": "team/handbook"}}`},
+		},
+		"invalid TeamRepositoryMap": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", TeamRepositoryMap: `{"team1": `},
+			ExpectedError: "TeamRepositoryMap must be a JSON object mapping team IDs to objects mapping documentation types to owner/repo: unexpected end of JSON input",
+		},
+		"unknown type in TeamRepositoryMap": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", TeamRepositoryMap: `{"team1": {"sales": "team/sales"}}`},
+			ExpectedError: `TeamRepositoryMap entry for team team1 has unknown documentation type "sales"`,
+		},
+		"invalid repository in TeamRepositoryMap": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", TeamRepositoryMap: `{"team1": {"admin": "admin"}}`},
+			ExpectedError: `TeamRepositoryMap entry for type admin of team team1 is invalid: repository "admin" is not in owner/repo form`,
+		},
+		"on-call schedule": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", UseOnCallSchedule: true, OnCallSchedule: `{"channel1": {"monday": "alice"}}`},
+		},
+		"on-call schedule with unknown day": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", UseOnCallSchedule: true, OnCallSchedule: `{"channel1": {"mon": "alice"}}`},
+			ExpectedError: `OnCallSchedule entry for channel channel1 has unknown day "mon"`,
+		},
+		"on-call schedule without assignee": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", UseOnCallSchedule: true, OnCallSchedule: `{"channel1": {"monday": " "}}`},
+			ExpectedError: "OnCallSchedule entry for monday of channel channel1 has no assignee",
+		},
+		"CORS allowed origins": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", CORSAllowedOrigins: "http://localhost:9005, https://dev.example.com/"},
+		},
+		"invalid CORS allowed origin": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", CORSAllowedOrigins: "*"},
+			ExpectedError: `CORSAllowedOrigins entry "*" must be a scheme and host, such as http://localhost:9005`,
+		},
+		"invalid IdempotencyKeyExpirySeconds": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", IdempotencyKeyExpirySeconds: "0"},
+			ExpectedError: "IdempotencyKeyExpirySeconds must be a positive number of seconds",
+		},
+		"invalid AssignToAuthorMapping": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", AssignToAuthor: true, AssignToAuthorMapping: `["user"]`},
+			ExpectedError: "AssignToAuthorMapping must be a JSON object mapping Mattermost usernames to GitHub usernames: json: cannot unmarshal array into Go value of type map[string]string",
+		},
+		"AssignToAuthorMapping entry without GitHub username": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", AssignToAuthor: true, AssignToAuthorMapping: `{"user": " "}`},
+			ExpectedError: "AssignToAuthorMapping entry for user has no GitHub username",
+		},
+		"invalid CreateTimeout": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", CreateTimeout: "-5"},
+			ExpectedError: "CreateTimeout must be a positive number of seconds",
+		},
+		"invalid MaxThreadMessages": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", MaxThreadMessages: "none"},
+			ExpectedError: "MaxThreadMessages must be a positive number of posts",
+		},
+		"invalid RedactPatterns": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", RedactPatterns: "(unclosed"},
+			ExpectedError: "RedactPatterns entry \"(unclosed\" is not a valid regular expression: error parsing regexp: missing closing ): `(unclosed`",
+		},
+		"invalid BlockedPatterns": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", BlockedPatterns: "ghp_[A-Za-z0-9]{36}\n[unclosed"},
+			ExpectedError: "BlockedPatterns entry \"[unclosed\" is not a valid regular expression: error parsing regexp: missing closing ]: `[unclosed`",
+		},
+		"unknown LockReason": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", LockIssues: true, LockReason: "policy"},
+			ExpectedError: `unknown LockReason "policy", expected off-topic, too heated, resolved or spam`,
+		},
+		"Organization only": {
+			Configuration: &configuration{GitHubAPIKey: "key", Organization: "my-org", OrganizationRepository: "docs.site"},
+		},
+		"invalid Organization": {
+			Configuration: &configuration{GitHubAPIKey: "key", Organization: "my org"},
+			ExpectedError: `Organization "my org" is not a valid GitHub organization name`,
+		},
+		"invalid OrganizationRepository": {
+			Configuration: &configuration{GitHubAPIKey: "key", Organization: "my-org", OrganizationRepository: "my-org/docs"},
+			ExpectedError: `OrganizationRepository "my-org/docs" is not a valid repository name`,
+		},
+		"IssueMarker": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", IssueMarker: "docs-team:v2"},
+		},
+		"IssueMarker ending the comment": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", IssueMarker: "docup--"},
+			ExpectedError: `IssueMarker "docup--" may only contain letters, digits, colons, dots, underscores and single dashes`,
+		},
+		"Gitea": {
+			Configuration: &configuration{Provider: providerGitea, GiteaURL: "https://gitea.example.com", GiteaToken: "token", AdminRepository: "owner/admin"},
+		},
+		"Gitea without token": {
+			Configuration: &configuration{Provider: providerGitea, GiteaURL: "https://gitea.example.com", AdminRepository: "owner/admin"},
+			ExpectedError: "GiteaToken not configured",
+		},
+		"Gitea without URL": {
+			Configuration: &configuration{Provider: providerGitea, GiteaToken: "token", AdminRepository: "owner/admin"},
+			ExpectedError: `GiteaURL must be the absolute http or https URL of the Gitea instance, got ""`,
+		},
+		"type templates": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", TypeBodyTemplateMap: `{"admin": "{{.Body}}"}`, TypeTitleTemplateMap: `{"admin": "{{.Title}}"}`},
+		},
+		"type body template for unknown type": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", TypeBodyTemplateMap: `{"sales": "{{.Body}}"}`},
+			ExpectedError: `TypeBodyTemplateMap has unknown documentation type "sales"`,
+		},
+		"invalid type body template": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", TypeBodyTemplateMap: `{"admin": "{{.Body"}`},
+			ExpectedError: `failed to parse TypeBodyTemplateMap entry for type admin: template: body:1: unclosed action`,
+		},
+		"invalid type title template": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", TypeTitleTemplateMap: `{"admin": "{{end}}"}`},
+			ExpectedError: `failed to parse TypeTitleTemplateMap entry for type admin: template: title:1: unexpected {{end}}`,
+		},
+		"NotifyTeam": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", NotifyTeam: "@my-org/docs.team"},
+		},
+		"NotifyTeam without org": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", NotifyTeam: "@docs-team"},
+			ExpectedError: `NotifyTeam "@docs-team" must be a GitHub team in @org/team form`,
+		},
+		"repository without owner": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "admin"},
+			ExpectedError: `AdminRepository is invalid: repository "admin" is not in owner/repo form`,
+		},
+		"repository with extra path": {
+			Configuration: &configuration{GitHubAPIKey: "key", AdminRepository: "owner/admin", DeveloperRepository: "owner/developer/docs"},
+			ExpectedError: `DeveloperRepository is invalid: repository "owner/developer/docs" is not in owner/repo form`,
+		},
+		"invalid additional repository": {
+			Configuration: &configuration{GitHubAPIKey: "key", HandbookRepository: "owner/handbook, /handbook"},
+			ExpectedError: `HandbookRepository is invalid: repository "/handbook" is not in owner/repo form`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := tc.Configuration.IsValid()
+			if tc.ExpectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.ExpectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// submissionKeyPrefix prefixes the KV store keys of recent submissions, which are followed by the
+// ID of the marked post.
+const submissionKeyPrefix = "submission_"
+
+// submission records the issue a post was recently marked for documentation with. Both fields are
+// empty while the issue is being filed.
+type submission struct {
+	IssueURL    string `json:"issue_url"`
+	IssueNumber int    `json:"issue_number"`
+}
+
+// saveSubmission records that the given post was marked for documentation, expiring once the
+// configured cooldown has passed. Nothing is recorded when the cooldown is disabled.
+func (p *Plugin) saveSubmission(postID string, createResponse *CreateAPIResponse) error {
+	cooldown := p.getConfiguration().getSubmitCooldownSeconds()
+	if cooldown == 0 {
+		return nil
+	}
+
+	value, err := json.Marshal(&submission{IssueURL: createResponse.IssueURL, IssueNumber: createResponse.IssueNumber})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode submission")
+	}
+
+	if appErr := p.API.KVSetWithExpiry(submissionKeyPrefix+postID, value, cooldown); appErr != nil {
+		return errors.Wrap(appErr, "failed to save submission")
+	}
+	return nil
+}
+
+// reserveSubmission records that the given post is being marked for documentation, unless it was
+// marked within the configured cooldown or is being marked by another request, in which case that
+// submission is returned instead. The check and the reservation are made under submissionLock so
+// that concurrent requests cannot both pass the cooldown. It reports whether the post was
+// reserved, which it never is when the cooldown is disabled.
+func (p *Plugin) reserveSubmission(postID string) (*submission, bool, error) {
+	cooldown := p.getConfiguration().getSubmitCooldownSeconds()
+	if cooldown == 0 {
+		return nil, false, nil
+	}
+
+	p.submissionLock.Lock()
+	defer p.submissionLock.Unlock()
+
+	recent, err := p.getSubmission(postID)
+	if err != nil || recent != nil {
+		return recent, false, err
+	}
+
+	value, err := json.Marshal(&submission{})
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to encode submission")
+	}
+	if appErr := p.API.KVSetWithExpiry(submissionKeyPrefix+postID, value, cooldown); appErr != nil {
+		return nil, false, errors.Wrap(appErr, "failed to reserve submission")
+	}
+	return nil, true, nil
+}
+
+// releaseSubmission removes the reservation of the given post made by reserveSubmission, so that
+// it can be marked again once marking it failed.
+func (p *Plugin) releaseSubmission(postID string) error {
+	if appErr := p.API.KVDelete(submissionKeyPrefix + postID); appErr != nil {
+		return errors.Wrap(appErr, "failed to release submission")
+	}
+	return nil
+}
+
+// getSubmission returns the recent submission of the given post, or nil if it has not been
+// marked for documentation within the configured cooldown.
+func (p *Plugin) getSubmission(postID string) (*submission, error) {
+	if p.getConfiguration().getSubmitCooldownSeconds() == 0 {
+		return nil, nil
+	}
+
+	value, appErr := p.API.KVGet(submissionKeyPrefix + postID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to get submission")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var recent *submission
+	if err := json.Unmarshal(value, &recent); err != nil {
+		return nil, errors.Wrap(err, "failed to decode submission")
+	}
+	return recent, nil
+}
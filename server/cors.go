@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// corsAllowedHeaders are the request headers cross-origin requests to create issues may send,
+// which cover those set by the Mattermost webapp.
+const corsAllowedHeaders = "Content-Type, X-Requested-With, X-CSRF-Token"
+
+// getCORSAllowedOrigins returns the origins allowed to make cross-origin requests to create
+// issues. Only same-origin requests are allowed when it is empty.
+func (c *configuration) getCORSAllowedOrigins() []string {
+	origins := []string{}
+	for _, origin := range strings.Split(c.CORSAllowedOrigins, ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// validateCORSOrigin checks that origin is a scheme and host, such as http://localhost:9005.
+func validateCORSOrigin(origin string) error {
+	parsed, err := url.Parse(origin)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" {
+		return errors.Errorf("CORSAllowedOrigins entry %q must be a scheme and host, such as http://localhost:9005", origin)
+	}
+	return nil
+}
+
+// isCORSOriginAllowed reports whether origin is one of the configured allowed origins.
+func (c *configuration) isCORSOriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range c.getCORSAllowedOrigins() {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleCORS adds the CORS headers allowing the origin of r, if it is allowed, and answers
+// preflight requests. It returns true if r was a preflight request and has been answered.
+func (p *Plugin) handleCORS(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	allowed := p.getConfiguration().isCORSOriginAllowed(origin)
+	if allowed {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Add("Vary", "Origin")
+	}
+
+	if r.Method != http.MethodOptions {
+		return false
+	}
+
+	if !allowed {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return true
+	}
+	w.Header().Set("Access-Control-Allow-Methods", http.MethodPost)
+	w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
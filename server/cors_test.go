@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateCORS(t *testing.T) {
+	for name, tc := range map[string]struct {
+		AllowedOrigins      string
+		Method              string
+		Origin              string
+		ExpectedStatus      int
+		ExpectedAllowOrigin string
+	}{
+		"preflight from allowed origin": {
+			AllowedOrigins:      "https://example.com, http://localhost:9005/",
+			Method:              http.MethodOptions,
+			Origin:              "http://localhost:9005",
+			ExpectedStatus:      http.StatusNoContent,
+			ExpectedAllowOrigin: "http://localhost:9005",
+		},
+		"preflight from other origin": {
+			AllowedOrigins: "http://localhost:9005",
+			Method:         http.MethodOptions,
+			Origin:         "http://localhost:8080",
+			ExpectedStatus: http.StatusForbidden,
+		},
+		"preflight with same-origin only": {
+			Method:         http.MethodOptions,
+			Origin:         "http://localhost:9005",
+			ExpectedStatus: http.StatusForbidden,
+		},
+		"request from allowed origin": {
+			AllowedOrigins:      "http://localhost:9005",
+			Method:              http.MethodGet,
+			Origin:              "http://localhost:9005",
+			ExpectedStatus:      http.StatusMethodNotAllowed,
+			ExpectedAllowOrigin: "http://localhost:9005",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			defer api.AssertExpectations(t)
+
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(&configuration{AdminRepository: "owner/repo", CORSAllowedOrigins: tc.AllowedOrigins})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.Method, "/create", nil)
+			r.Header.Set("Origin", tc.Origin)
+
+			plugin.ServeHTTP(nil, w, r)
+
+			result := w.Result()
+			assert.Equal(tc.ExpectedStatus, result.StatusCode)
+			assert.Equal(tc.ExpectedAllowOrigin, result.Header.Get("Access-Control-Allow-Origin"))
+			if tc.ExpectedStatus == http.StatusNoContent {
+				assert.Equal(http.MethodPost, result.Header.Get("Access-Control-Allow-Methods"))
+				assert.Contains(result.Header.Get("Access-Control-Allow-Headers"), "Content-Type")
+				assert.Equal("true", result.Header.Get("Access-Control-Allow-Credentials"))
+			}
+		})
+	}
+}
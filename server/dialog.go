@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const dialogCallbackID = "docup_create"
+
+// OpenDialogAPIRequest asks to open the Doc Up dialog for a post. Its fields match those of a
+// post action integration request, so the endpoint can also back an interactive button.
+type OpenDialogAPIRequest struct {
+	TriggerID string `json:"trigger_id"`
+	PostID    string `json:"post_id"`
+}
+
+// handleOpenDialog opens an interactive dialog asking for the type, title and body of the issue
+// to create for a post.
+func (p *Plugin) handleOpenDialog(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dialogRequest *OpenDialogAPIRequest
+	if err := json.NewDecoder(r.Body).Decode(&dialogRequest); err != nil || dialogRequest == nil || dialogRequest.TriggerID == "" || dialogRequest.PostID == "" {
+		http.Error(w, "trigger_id and post_id are required", http.StatusBadRequest)
+		return
+	}
+
+	docPost, appErr := p.API.GetPost(dialogRequest.PostID)
+	if (appErr != nil && appErr.StatusCode == http.StatusNotFound) || (appErr == nil && docPost.DeleteAt != 0) {
+		http.Error(w, postNotFoundMessage, http.StatusNotFound)
+		return
+	}
+	if appErr != nil {
+		p.API.LogError("Unable to get post", "user_id", userID, "post_id", dialogRequest.PostID, "error", appErr.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !p.API.HasPermissionToChannel(userID, docPost.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		http.Error(w, "You do not have permission to read this post", http.StatusForbidden)
+		return
+	}
+
+	typeOptions := []*model.PostActionOptions{}
+	for _, issueType := range p.getConfiguration().getEnabledTypes() {
+		typeOptions = append(typeOptions, &model.PostActionOptions{
+			Text:  issueTypeDisplayNames[issueType],
+			Value: issueType,
+		})
+	}
+
+	siteURL, err := p.getSiteURL()
+	if err != nil {
+		p.API.LogError("Unable to get site URL", "user_id", userID, "error", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	appErr = p.API.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: dialogRequest.TriggerID,
+		URL:       strings.TrimSuffix(siteURL, "/") + "/plugins/" + manifest.ID + "/dialog/submit",
+		Dialog: model.Dialog{
+			CallbackId:  dialogCallbackID,
+			Title:       "Doc Up",
+			SubmitLabel: "Mark for Documentation",
+			State:       docPost.Id,
+			Elements: []model.DialogElement{
+				{
+					DisplayName: "Type",
+					Name:        "type",
+					Type:        "select",
+					HelpText:    "What type of documentation is this?",
+					Options:     typeOptions,
+				},
+				{
+					DisplayName: "Short title",
+					Name:        "title",
+					Type:        "text",
+					Placeholder: "One line summary of what is being documented",
+				},
+				{
+					DisplayName: "Message to document",
+					Name:        "body",
+					Type:        "textarea",
+					Default:     docPost.Message,
+					MaxLength:   model.POST_MESSAGE_MAX_RUNES_V2,
+				},
+			},
+		},
+	})
+	if appErr != nil {
+		p.API.LogError("Unable to open dialog", "user_id", userID, "post_id", docPost.Id, "error", appErr.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleSubmitDialog validates a submission of the Doc Up dialog and creates the issue.
+func (p *Plugin) handleSubmitDialog(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var submitRequest *model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&submitRequest); err != nil || submitRequest == nil {
+		http.Error(w, "Invalid dialog submission", http.StatusBadRequest)
+		return
+	}
+
+	if submitRequest.UserId != userID || submitRequest.CallbackId != dialogCallbackID {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if submitRequest.Cancelled {
+		return
+	}
+
+	createRequest := &CreateAPIRequest{
+		Type:   getSubmissionString(submitRequest.Submission, "type"),
+		Title:  strings.TrimSpace(getSubmissionString(submitRequest.Submission, "title")),
+		Body:   getSubmissionString(submitRequest.Submission, "body"),
+		PostID: submitRequest.State,
+	}
+
+	errs := map[string]string{}
+	if len(p.getConfiguration().getRepositories(createRequest.Type)) == 0 {
+		errs["type"] = "Please select a documentation type."
+	}
+	if createRequest.Title == "" {
+		errs["title"] = "Please enter a title."
+	}
+	if strings.TrimSpace(createRequest.Body) == "" {
+		errs["body"] = "Please enter the message to document."
+	}
+
+	response := &model.SubmitDialogResponse{}
+	if len(errs) > 0 {
+		response.Errors = errs
+	} else {
+		createResponse, err := p.createIssueFromPost(p.pluginContext(), userID, createRequest)
+		p.metrics.recordCreate(createResponse, err)
+		if err != nil {
+			p.API.SendEphemeralPost(userID, &model.Post{
+				UserId:    p.botUserID,
+				ChannelId: submitRequest.ChannelId,
+				Message:   "Unable to create the documentation issue: " + err.Error(),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.API.LogError("Unable to encode JSON", "user_id", userID, "error", err.Error())
+	}
+}
+
+// getSubmissionString returns the string value of a dialog element, or an empty string.
+func getSubmissionString(submission map[string]interface{}, name string) string {
+	value, _ := submission[name].(string)
+	return value
+}
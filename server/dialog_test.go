@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOpenDialog(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Post           *model.Post
+		PostErr        *model.AppError
+		SiteURL        *string
+		ExpectedStatus int
+		ExpectedBody   string
+		ExpectedURL    string
+	}{
+		"dialog opened": {
+			Post:           &model.Post{Id: "post1", ChannelId: "channel1", Message: "message"},
+			SiteURL:        NewString("https://mattermost.example.com/"),
+			ExpectedStatus: http.StatusOK,
+			ExpectedURL:    "https://mattermost.example.com/plugins/" + manifest.ID + "/dialog/submit",
+		},
+		"post not found": {
+			PostErr:        model.NewAppError("GetPost", "app.post.get.app_error", nil, "", http.StatusNotFound),
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   postNotFoundMessage,
+		},
+		"post deleted": {
+			Post:           &model.Post{Id: "post1", ChannelId: "channel1", Message: "message", DeleteAt: 1},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   postNotFoundMessage,
+		},
+		"no site URL": {
+			Post:           &model.Post{Id: "post1", ChannelId: "channel1", Message: "message"},
+			ExpectedStatus: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			api.On("GetPost", "post1").Return(tc.Post, tc.PostErr)
+			if tc.Post != nil && tc.Post.DeleteAt == 0 {
+				api.On("HasPermissionToChannel", "user1", "channel1", model.PERMISSION_READ_CHANNEL).Return(true)
+				api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: tc.SiteURL}})
+			}
+			if tc.ExpectedStatus == http.StatusInternalServerError {
+				api.On("LogError", logArguments(2)...).Return()
+			}
+			openedURL := ""
+			if tc.ExpectedURL != "" {
+				api.On("OpenInteractiveDialog", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+					dialogRequest := args.Get(0).(model.OpenDialogRequest)
+					assert.Equal("trigger1", dialogRequest.TriggerId)
+					assert.Equal("post1", dialogRequest.Dialog.State)
+					openedURL = dialogRequest.URL
+				})
+			}
+			defer api.AssertExpectations(t)
+
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(&configuration{AdminRepository: "owner/repo"})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/dialog", bytes.NewBufferString(`{"trigger_id":"trigger1","post_id":"post1"}`))
+			r.Header.Set("Mattermost-User-ID", "user1")
+
+			plugin.ServeHTTP(nil, w, r)
+
+			result := w.Result()
+			assert.Equal(tc.ExpectedStatus, result.StatusCode)
+			if tc.ExpectedBody != "" {
+				assert.Equal(tc.ExpectedBody, strings.TrimSpace(w.Body.String()))
+			}
+			assert.Equal(tc.ExpectedURL, openedURL)
+		})
+	}
+}
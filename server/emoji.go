@@ -0,0 +1,65 @@
+package main
+
+import "regexp"
+
+// emojiShortcodePattern matches Mattermost emoji shortcodes such as :smile:.
+var emojiShortcodePattern = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+
+// emojiShortcodes maps the most common Mattermost emoji shortcodes to their unicode emoji.
+var emojiShortcodes = map[string]string{
+	"+1":                    "👍",
+	"-1":                    "👎",
+	"100":                   "💯",
+	"bangbang":              "‼️",
+	"books":                 "📚",
+	"bug":                   "🐛",
+	"clap":                  "👏",
+	"confused":              "😕",
+	"cry":                   "😢",
+	"disappointed":          "😞",
+	"eyes":                  "👀",
+	"fire":                  "🔥",
+	"grin":                  "😁",
+	"grinning":              "😀",
+	"heart":                 "❤️",
+	"heavy_check_mark":      "✔️",
+	"joy":                   "😂",
+	"laughing":              "😆",
+	"memo":                  "📝",
+	"muscle":                "💪",
+	"ok_hand":               "👌",
+	"pencil":                "📝",
+	"point_right":           "👉",
+	"pray":                  "🙏",
+	"question":              "❓",
+	"raised_hands":          "🙌",
+	"rocket":                "🚀",
+	"slightly_smiling_face": "🙂",
+	"smile":                 "😄",
+	"smiley":                "😃",
+	"sob":                   "😭",
+	"sunglasses":            "😎",
+	"tada":                  "🎉",
+	"thinking":              "🤔",
+	"thinking_face":         "🤔",
+	"thumbsdown":            "👎",
+	"thumbsup":              "👍",
+	"warning":               "⚠️",
+	"wave":                  "👋",
+	"white_check_mark":      "✅",
+	"wink":                  "😉",
+	"x":                     "❌",
+}
+
+// convertEmoji replaces known emoji shortcodes outside of code with their unicode emoji, leaving
+// unknown shortcodes unchanged.
+func convertEmoji(body string) string {
+	return replaceOutsideCode(body, func(text string) string {
+		return emojiShortcodePattern.ReplaceAllStringFunc(text, func(shortcode string) string {
+			if emoji, ok := emojiShortcodes[shortcode[1:len(shortcode)-1]]; ok {
+				return emoji
+			}
+			return shortcode
+		})
+	})
+}
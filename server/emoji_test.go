@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertEmoji(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Body     string
+		Expected string
+	}{
+		"no emoji": {
+			Body:     "Please document this.",
+			Expected: "Please document this.",
+		},
+		"several emoji": {
+			Body:     "Thanks :+1: this is :fire: :tada:",
+			Expected: "Thanks 👍 this is 🔥 🎉",
+		},
+		"unknown shortcode": {
+			Body:     "Works with :custom_emoji: and :smile:",
+			Expected: "Works with :custom_emoji: and 😄",
+		},
+		"times are not shortcodes": {
+			Body:     "Meet at 10:30:00 :wave:",
+			Expected: "Meet at 10:30:00 👋",
+		},
+		"code is left untouched": {
+			Body:     "Run `echo :smile:` :smile:\n```\n:tada:\n```",
+			Expected: "Run `echo :smile:` 😄\n```\n:tada:\n```",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, convertEmoji(tc.Body))
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// giteaLabelsPageSize is the number of labels requested per page when resolving label names.
+const giteaLabelsPageSize = 50
+
+// giteaIssueCreator creates issues through the Gitea v1 REST API.
+type giteaIssueCreator struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+func newGiteaIssueCreator(baseURL, token string) *giteaIssueCreator {
+	return &giteaIssueCreator{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// giteaError is returned when Gitea responds with a non-2xx status.
+type giteaError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *giteaError) Error() string {
+	return fmt.Sprintf("Gitea responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+type giteaIssueRequest struct {
+	Title     string   `json:"title"`
+	Body      string   `json:"body,omitempty"`
+	Assignees []string `json:"assignees,omitempty"`
+	Labels    []int64  `json:"labels,omitempty"`
+}
+
+type giteaIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+}
+
+type giteaLabel struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// CreateIssue creates an issue in the owner/repo repository. Gitea only accepts label IDs, so
+// labels are looked up by name in the repository, and those it does not have are ignored.
+func (c *giteaIssueCreator) CreateIssue(ctx context.Context, owner, repo string, req *github.IssueRequest) (*github.Issue, error) {
+	issueRequest := &giteaIssueRequest{
+		Title: req.GetTitle(),
+		Body:  req.GetBody(),
+	}
+	if req.Assignees != nil {
+		issueRequest.Assignees = *req.Assignees
+	}
+	if req.Labels != nil && len(*req.Labels) > 0 {
+		labelIDs, err := c.getLabelIDs(ctx, owner, repo, *req.Labels)
+		if err != nil {
+			return nil, err
+		}
+		issueRequest.Labels = labelIDs
+	}
+
+	payload, err := json.Marshal(issueRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode Gitea issue")
+	}
+
+	var issue giteaIssue
+	if err := c.do(ctx, http.MethodPost, c.repoURL(owner, repo)+"/issues", payload, &issue); err != nil {
+		return nil, err
+	}
+
+	return &github.Issue{
+		Number:  &issue.Number,
+		Title:   &issue.Title,
+		State:   &issue.State,
+		HTMLURL: &issue.HTMLURL,
+	}, nil
+}
+
+// getLabelIDs returns the IDs of the labels of the repository with the given names, matched
+// case-insensitively.
+func (c *giteaIssueCreator) getLabelIDs(ctx context.Context, owner, repo string, names []string) ([]int64, error) {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[strings.ToLower(name)] = true
+	}
+
+	labelIDs := []int64{}
+	for page := 1; ; page++ {
+		var labels []giteaLabel
+		query := url.Values{"page": {strconv.Itoa(page)}, "limit": {strconv.Itoa(giteaLabelsPageSize)}}
+		if err := c.do(ctx, http.MethodGet, c.repoURL(owner, repo)+"/labels?"+query.Encode(), nil, &labels); err != nil {
+			return nil, err
+		}
+		for _, label := range labels {
+			if wanted[strings.ToLower(label.Name)] {
+				labelIDs = append(labelIDs, label.ID)
+			}
+		}
+		if len(labels) < giteaLabelsPageSize {
+			return labelIDs, nil
+		}
+	}
+}
+
+// repoURL returns the API URL of the owner/repo repository.
+func (c *giteaIssueCreator) repoURL(owner, repo string) string {
+	return c.baseURL + "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+// do sends a request with the given JSON payload, if any, and decodes the JSON response into
+// result.
+func (c *giteaIssueCreator) do(ctx context.Context, method, requestURL string, payload []byte, result interface{}) error {
+	httpRequest, err := http.NewRequest(method, requestURL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(err, "failed to build Gitea request")
+	}
+	httpRequest = httpRequest.WithContext(ctx)
+	if payload != nil {
+		httpRequest.Header.Set("Content-Type", "application/json")
+	}
+	httpRequest.Header.Set("Authorization", "token "+c.token)
+
+	resp, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errorResponse struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errorResponse)
+		return &giteaError{StatusCode: resp.StatusCode, Message: errorResponse.Message}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return errors.Wrap(err, "failed to decode Gitea response")
+	}
+	return nil
+}
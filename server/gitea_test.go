@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGiteaCreateIssue(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("token token", r.Header.Get("Authorization"))
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/repos/owner/repo/labels":
+			assert.Equal("50", r.URL.Query().Get("limit"))
+			if r.URL.Query().Get("page") == "1" {
+				labels := []string{`{"id": 1, "name": "Docs"}`}
+				for id := 2; id <= giteaLabelsPageSize; id++ {
+					labels = append(labels, fmt.Sprintf(`{"id": %d, "name": "label%d"}`, id, id))
+				}
+				_, _ = w.Write([]byte("[" + strings.Join(labels, ",") + "]"))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"id": 99, "name": "admin"}]`))
+		case "POST /api/v1/repos/owner/repo/issues":
+			var request giteaIssueRequest
+			assert.Nil(json.NewDecoder(r.Body).Decode(&request))
+			assert.Equal("title", request.Title)
+			assert.Equal("body", request.Body)
+			assert.Equal([]string{"writer"}, request.Assignees)
+			assert.Equal([]int64{1, 99}, request.Labels, "unknown labels should be ignored")
+
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number": 42, "title": "title", "state": "open", "html_url": "https://gitea.example.com/owner/repo/issues/42"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	labels := []string{"docs", "admin", "missing"}
+	assignees := []string{"writer"}
+	issue, err := newGiteaIssueCreator(server.URL+"/", "token").CreateIssue(context.Background(), "owner", "repo", &github.IssueRequest{
+		Title:     NewString("title"),
+		Body:      NewString("body"),
+		Labels:    &labels,
+		Assignees: &assignees,
+	})
+	assert.Nil(err)
+	assert.Equal(42, issue.GetNumber())
+	assert.Equal("https://gitea.example.com/owner/repo/issues/42", issue.GetHTMLURL())
+}
+
+func TestGiteaCreateIssueError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "repository does not exist"}`))
+	}))
+	defer server.Close()
+
+	_, err := newGiteaIssueCreator(server.URL, "token").CreateIssue(context.Background(), "owner", "repo", &github.IssueRequest{Title: NewString("title")})
+	assert.Equal(t, &giteaError{StatusCode: http.StatusNotFound, Message: "repository does not exist"}, err)
+	assert.False(t, isTransientError(err))
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// authModeToken authenticates with the GitHubAPIKey personal access token, and authModeApp as
+	// an installation of the GitHub App identified by GitHubAppID and GitHubAppInstallationID.
+	authModeToken = "token"
+	authModeApp   = "app"
+
+	// appJWTLifetime is how long the JWTs identifying the GitHub App are valid for. GitHub rejects
+	// JWTs valid for more than ten minutes.
+	appJWTLifetime = 9 * time.Minute
+
+	// appJWTClockSkew backdates the JWTs to allow for the clock of GitHub being behind.
+	appJWTClockSkew = time.Minute
+)
+
+// appInstallationTokenSource mints installation access tokens for a GitHub App. Tokens expire
+// after an hour, so it is meant to be wrapped with oauth2.ReuseTokenSource.
+type appInstallationTokenSource struct {
+	client   *http.Client
+	tokenURL string
+	appID    string
+	key      *rsa.PrivateKey
+}
+
+// newAppInstallationTokenSource returns a token source for the installation of the GitHub App
+// configured, requesting tokens with the HTTP client carried by ctx, if any.
+func newAppInstallationTokenSource(ctx context.Context, config *configuration) (oauth2.TokenSource, error) {
+	key, err := parseAppPrivateKey(config.GitHubAppPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := "https://api.github.com/"
+	if config.GitHubBaseURL != "" {
+		baseURL = strings.TrimSuffix(config.GitHubBaseURL, "/") + "/"
+	}
+
+	client := http.DefaultClient
+	if contextClient, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		client = contextClient
+	}
+
+	return oauth2.ReuseTokenSource(nil, &appInstallationTokenSource{
+		client:   client,
+		tokenURL: fmt.Sprintf("%sapp/installations/%s/access_tokens", baseURL, strings.TrimSpace(config.GitHubAppInstallationID)),
+		appID:    strings.TrimSpace(config.GitHubAppID),
+		key:      key,
+	}), nil
+}
+
+// Token requests a new installation access token, authenticating as the GitHub App.
+func (s *appInstallationTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := signAppJWT(s.appID, s.key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, s.tokenURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create installation token request")
+	}
+	request.Header.Set("Authorization", "Bearer "+jwt)
+	request.Header.Set("Accept", "application/vnd.github.machine-man-preview+json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to request installation token")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		return nil, errors.Errorf("failed to request installation token: GitHub responded with status %d", response.StatusCode)
+	}
+
+	var installationToken struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&installationToken); err != nil {
+		return nil, errors.Wrap(err, "failed to decode installation token")
+	}
+
+	return &oauth2.Token{
+		AccessToken: installationToken.Token,
+		TokenType:   "token",
+		Expiry:      installationToken.ExpiresAt,
+	}, nil
+}
+
+// signAppJWT returns a JWT identifying the GitHub App with the given ID, signed with its private
+// key.
+func signAppJWT(appID string, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode JWT header")
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode JWT claims")
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hashed := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign JWT")
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseAppPrivateKey decodes the PEM encoded private key of a GitHub App, as downloaded from its
+// settings.
+func parseAppPrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(privateKey)))
+	if block == nil {
+		return nil, errors.New("GitHubAppPrivateKey must be a PEM encoded private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "GitHubAppPrivateKey is not a valid private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("GitHubAppPrivateKey must be an RSA private key")
+	}
+	return key, nil
+}
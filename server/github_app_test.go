@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAppPrivateKey returns an RSA private key and its PEM encoding.
+func newTestAppPrivateKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func TestSignAppJWT(t *testing.T) {
+	assert := assert.New(t)
+
+	key, _ := newTestAppPrivateKey(t)
+	now := time.Unix(1500000000, 0)
+
+	jwt, err := signAppJWT("12", key, now)
+	require.Nil(t, err)
+
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3)
+
+	var claims struct {
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Issuer    string `json:"iss"`
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(payload, &claims))
+	assert.Equal("12", claims.Issuer)
+	assert.Equal(now.Add(-time.Minute).Unix(), claims.IssuedAt)
+	assert.Equal(now.Add(9*time.Minute).Unix(), claims.ExpiresAt)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.Nil(t, err)
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.Nil(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hashed[:], signature))
+}
+
+func TestGitHubAppClient(t *testing.T) {
+	assert := assert.New(t)
+
+	key, privateKey := newTestAppPrivateKey(t)
+
+	tokenRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v3/app/installations/34/access_tokens":
+			tokenRequests++
+			jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			parts := strings.Split(jwt, ".")
+			if assert.Len(parts, 3) {
+				signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+				hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+				assert.Nil(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hashed[:], signature))
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token": "installation-token", "expires_at": "` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+		case "GET /api/v3/repos/owner/repo":
+			assert.Equal("token installation-token", r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"full_name": "owner/repo"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := &configuration{
+		AuthMode:                authModeApp,
+		GitHubAppID:             "12",
+		GitHubAppInstallationID: "34",
+		GitHubAppPrivateKey:     privateKey,
+		GitHubBaseURL:           server.URL + "/api/v3/",
+	}
+	client, err := newGitHubClient(config)
+	require.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		repository, _, err := client.Repositories.Get(context.Background(), "owner", "repo")
+		require.Nil(t, err)
+		assert.Equal("owner/repo", repository.GetFullName())
+	}
+	assert.Equal(1, tokenRequests, "the installation token should be reused until it expires")
+}
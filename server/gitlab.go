@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// defaultGitLabURL is used when GitLabURL is not configured.
+const defaultGitLabURL = "https://gitlab.com"
+
+// gitlabIssueCreator creates issues through the GitLab v4 REST API.
+type gitlabIssueCreator struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+func newGitLabIssueCreator(baseURL, token string) *gitlabIssueCreator {
+	if baseURL == "" {
+		baseURL = defaultGitLabURL
+	}
+
+	return &gitlabIssueCreator{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// gitlabError is returned when GitLab responds with a non-2xx status.
+type gitlabError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *gitlabError) Error() string {
+	return fmt.Sprintf("GitLab responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+type gitlabIssueRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Labels      string `json:"labels,omitempty"`
+}
+
+type gitlabIssue struct {
+	IID    int    `json:"iid"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	WebURL string `json:"web_url"`
+}
+
+// CreateIssue creates an issue in the owner/repo project. Assignees are ignored, since GitLab
+// only accepts numeric user IDs.
+func (c *gitlabIssueCreator) CreateIssue(ctx context.Context, owner, repo string, req *github.IssueRequest) (*github.Issue, error) {
+	issueRequest := &gitlabIssueRequest{
+		Title:       req.GetTitle(),
+		Description: req.GetBody(),
+	}
+	if req.Labels != nil {
+		issueRequest.Labels = strings.Join(*req.Labels, ",")
+	}
+
+	payload, err := json.Marshal(issueRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode GitLab issue")
+	}
+
+	project := url.PathEscape(owner + "/" + repo)
+	httpRequest, err := http.NewRequest(http.MethodPost, c.baseURL+"/api/v4/projects/"+project+"/issues", bytes.NewReader(payload))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build GitLab request")
+	}
+	httpRequest = httpRequest.WithContext(ctx)
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("PRIVATE-TOKEN", c.token)
+
+	resp, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errorResponse struct {
+			Message interface{} `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errorResponse)
+		return nil, &gitlabError{StatusCode: resp.StatusCode, Message: fmt.Sprint(errorResponse.Message)}
+	}
+
+	var issue gitlabIssue
+	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
+		return nil, errors.Wrap(err, "failed to decode GitLab issue")
+	}
+
+	return &github.Issue{
+		Number:  &issue.IID,
+		Title:   &issue.Title,
+		State:   &issue.State,
+		HTMLURL: &issue.WebURL,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitLabCreateIssue(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/api/v4/projects/owner%2Frepo/issues", r.URL.RawPath)
+		assert.Equal("token", r.Header.Get("PRIVATE-TOKEN"))
+
+		var request gitlabIssueRequest
+		assert.Nil(json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal("title", request.Title)
+		assert.Equal("body", request.Description)
+		assert.Equal("docs,admin", request.Labels)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"iid": 42, "title": "title", "state": "opened", "web_url": "https://gitlab.example.com/owner/repo/issues/42"}`))
+	}))
+	defer server.Close()
+
+	labels := []string{"docs", "admin"}
+	issue, err := newGitLabIssueCreator(server.URL, "token").CreateIssue(context.Background(), "owner", "repo", &github.IssueRequest{
+		Title:  NewString("title"),
+		Body:   NewString("body"),
+		Labels: &labels,
+	})
+	assert.Nil(err)
+	assert.Equal(42, issue.GetNumber())
+	assert.Equal("https://gitlab.example.com/owner/repo/issues/42", issue.GetHTMLURL())
+}
+
+func TestGitLabCreateIssueError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "404 Project Not Found"}`))
+	}))
+	defer server.Close()
+
+	_, err := newGitLabIssueCreator(server.URL, "token").CreateIssue(context.Background(), "owner", "repo", &github.IssueRequest{Title: NewString("title")})
+	assert.Equal(t, &gitlabError{StatusCode: http.StatusNotFound, Message: "404 Project Not Found"}, err)
+	assert.False(t, isTransientError(err))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	// healthCacheDuration is how long the outcome of checking that GitHub is reachable is reused
+	// for, so that frequent monitoring does not use up the rate limit.
+	healthCacheDuration = 5 * time.Second
+
+	healthStatusOK    = "ok"
+	healthStatusError = "error"
+
+	githubHealthReachable     = "reachable"
+	githubHealthUnreachable   = "unreachable"
+	githubHealthMisconfigured = "misconfigured"
+	githubHealthNotUsed       = "not used"
+)
+
+// HealthAPIResponse reports whether the plugin is able to file issues.
+type HealthAPIResponse struct {
+	Status string `json:"status"`
+	GitHub string `json:"github"`
+}
+
+// healthCheck caches the outcome of the last check that GitHub is reachable.
+type healthCheck struct {
+	lock sync.Mutex
+
+	// client is the GitHub client the check was made with, so that the check is repeated once the
+	// client is rebuilt with new settings.
+	client    *github.Client
+	checkedAt time.Time
+	err       error
+}
+
+// checkGitHub reports whether GitHub is reachable with the given client, making a request for the
+// rate limits, which do not count against them, at most once every healthCacheDuration.
+func (p *Plugin) checkGitHub(client *github.Client) error {
+	p.health.lock.Lock()
+	defer p.health.lock.Unlock()
+
+	if p.health.client == client && time.Since(p.health.checkedAt) < healthCacheDuration {
+		return p.health.err
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	_, _, err := client.RateLimits(ctx)
+
+	p.health.client = client
+	p.health.checkedAt = time.Now()
+	p.health.err = err
+
+	return err
+}
+
+// handleHealth serves the health of the plugin to external monitoring, with a 503 status if the
+// plugin is misconfigured or GitHub is unreachable. It requires no authentication and reveals no
+// details of the failure, which are logged instead.
+func (p *Plugin) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.API.LogWarn("Health check failed: invalid configuration", "error", err.Error())
+		_ = writeJSON(w, http.StatusServiceUnavailable, &HealthAPIResponse{Status: healthStatusError, GitHub: githubHealthMisconfigured})
+		return
+	}
+
+	if !config.isGitHub() {
+		_ = writeJSON(w, http.StatusOK, &HealthAPIResponse{Status: healthStatusOK, GitHub: githubHealthNotUsed})
+		return
+	}
+
+	client := p.getGitHubClient()
+	if client == nil {
+		p.API.LogWarn("Health check failed: no GitHub client")
+		_ = writeJSON(w, http.StatusServiceUnavailable, &HealthAPIResponse{Status: healthStatusError, GitHub: githubHealthMisconfigured})
+		return
+	}
+
+	if err := p.checkGitHub(client); err != nil {
+		p.API.LogWarn("Health check failed: GitHub is unreachable", "error", err.Error())
+		_ = writeJSON(w, http.StatusServiceUnavailable, &HealthAPIResponse{Status: healthStatusError, GitHub: githubHealthUnreachable})
+		return
+	}
+
+	_ = writeJSON(w, http.StatusOK, &HealthAPIResponse{Status: healthStatusOK, GitHub: githubHealthReachable})
+}
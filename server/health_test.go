@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Configuration   *configuration
+		RateLimitStatus int
+		ExpectedStatus  int
+		ExpectedGitHub  string
+	}{
+		"reachable": {
+			Configuration:   &configuration{GitHubAPIKey: "key", AdminRepository: "owner/repo"},
+			RateLimitStatus: http.StatusOK,
+			ExpectedStatus:  http.StatusOK,
+			ExpectedGitHub:  githubHealthReachable,
+		},
+		"unreachable": {
+			Configuration:   &configuration{GitHubAPIKey: "key", AdminRepository: "owner/repo"},
+			RateLimitStatus: http.StatusBadGateway,
+			ExpectedStatus:  http.StatusServiceUnavailable,
+			ExpectedGitHub:  githubHealthUnreachable,
+		},
+		"misconfigured": {
+			Configuration:  &configuration{AdminRepository: "owner/repo"},
+			ExpectedStatus: http.StatusServiceUnavailable,
+			ExpectedGitHub: githubHealthMisconfigured,
+		},
+		"not used": {
+			Configuration:  &configuration{Provider: providerGitLab, GitLabToken: "token", AdminRepository: "group/project"},
+			ExpectedStatus: http.StatusOK,
+			ExpectedGitHub: githubHealthNotUsed,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			if tc.ExpectedStatus != http.StatusOK {
+				api.On("LogWarn", logArguments(1)...).Return()
+			}
+			defer api.AssertExpectations(t)
+
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(tc.Configuration)
+
+			requests := 0
+			plugin.github = newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.URL.Path != "/rate_limit" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tc.RateLimitStatus)
+				_, _ = w.Write([]byte(`{"resources": {"core": {"limit": 5000, "remaining": 4990, "reset": 1500000000}}}`))
+			})
+
+			for i := 0; i < 2; i++ {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodGet, "/health", nil)
+				plugin.ServeHTTP(nil, w, r)
+
+				result := w.Result()
+				assert.Equal(tc.ExpectedStatus, result.StatusCode)
+
+				var response HealthAPIResponse
+				assert.Nil(json.NewDecoder(result.Body).Decode(&response))
+				assert.Equal(tc.ExpectedGitHub, response.GitHub)
+				if tc.ExpectedStatus == http.StatusOK {
+					assert.Equal(healthStatusOK, response.Status)
+				} else {
+					assert.Equal(healthStatusError, response.Status)
+				}
+			}
+
+			if tc.RateLimitStatus != 0 {
+				assert.Equal(1, requests, "the GitHub check should be cached")
+			} else {
+				assert.Equal(0, requests)
+			}
+		})
+	}
+}
+
+func TestHealthRejectsWrongMethod(t *testing.T) {
+	plugin := Plugin{}
+	plugin.setConfiguration(&configuration{})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/health", nil)
+	plugin.ServeHTTP(nil, w, r)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Result().StatusCode)
+	assert.Equal(t, http.MethodGet, w.Result().Header.Get("Allow"))
+}
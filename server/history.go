@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// userHistoryKeyPrefix prefixes the KV store keys of user histories, which are followed by the
+	// ID of the requesting user.
+	userHistoryKeyPrefix = "history_"
+
+	// maxUserHistory caps the number of requests kept in the history of each user, dropping the
+	// oldest ones first.
+	maxUserHistory = 20
+)
+
+// userHistoryEntry records an issue filed at the request of a user.
+type userHistoryEntry struct {
+	Repository  string `json:"repository"`
+	IssueNumber int    `json:"issue_number"`
+	IssueURL    string `json:"issue_url"`
+	Title       string `json:"title"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// addUserHistory prepends the given entry to the history of the given user, capping it to
+// maxUserHistory entries.
+func (p *Plugin) addUserHistory(userID string, entry *userHistoryEntry) error {
+	p.historyLock.Lock()
+	defer p.historyLock.Unlock()
+
+	history, err := p.getUserHistory(userID)
+	if err != nil {
+		return err
+	}
+
+	history = append([]*userHistoryEntry{entry}, history...)
+	if len(history) > maxUserHistory {
+		history = history[:maxUserHistory]
+	}
+
+	value, err := json.Marshal(history)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode user history")
+	}
+
+	if appErr := p.API.KVSet(userHistoryKeyPrefix+userID, value); appErr != nil {
+		return errors.Wrap(appErr, "failed to save user history")
+	}
+	return nil
+}
+
+// getUserHistory returns the history of the given user, most recent first.
+func (p *Plugin) getUserHistory(userID string) ([]*userHistoryEntry, error) {
+	value, appErr := p.API.KVGet(userHistoryKeyPrefix + userID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to get user history")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var history []*userHistoryEntry
+	if err := json.Unmarshal(value, &history); err != nil {
+		return nil, errors.Wrap(err, "failed to decode user history")
+	}
+	return history, nil
+}
+
+// newUserHistoryEntry returns a history entry for the given issue, filed now.
+func newUserHistoryEntry(ownerAndRepo string, number int, issueURL, title string) *userHistoryEntry {
+	return &userHistoryEntry{
+		Repository:  ownerAndRepo,
+		IssueNumber: number,
+		IssueURL:    issueURL,
+		Title:       title,
+		CreatedAt:   time.Now().Unix(),
+	}
+}
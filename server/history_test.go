@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	store := map[string][]byte{}
+
+	api := &plugintest.API{}
+	api.On("KVSet", "history_user1", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		store[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		return store[key]
+	}, nil)
+
+	plugin := Plugin{}
+	plugin.SetAPI(api)
+
+	history, err := plugin.getUserHistory("user1")
+	require.NoError(t, err)
+	assert.Empty(history)
+
+	for i := 1; i <= maxUserHistory+2; i++ {
+		require.NoError(t, plugin.addUserHistory("user1", &userHistoryEntry{
+			Repository:  "owner/repo",
+			IssueNumber: i,
+			IssueURL:    fmt.Sprintf("https://github.com/owner/repo/issues/%d", i),
+			Title:       "title",
+			CreatedAt:   int64(i),
+		}))
+	}
+
+	history, err = plugin.getUserHistory("user1")
+	require.NoError(t, err)
+	require.Len(t, history, maxUserHistory)
+	assert.Equal(maxUserHistory+2, history[0].IssueNumber, "the most recent request should come first")
+	assert.Equal(3, history[maxUserHistory-1].IssueNumber, "the oldest requests should be dropped")
+
+	history, err = plugin.getUserHistory("user2")
+	require.NoError(t, err)
+	assert.Empty(history)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// translationsDir is where translation files are found within the plugin bundle. Each file is
+// named after its locale, such as es.json, and holds a JSON object mapping message IDs to
+// translations.
+const translationsDir = "assets/i18n"
+
+// localizedMessage is a user-facing message that can be translated. Default is the English text,
+// which is used when no translation exists for the user's locale. Both may contain fmt verbs.
+type localizedMessage struct {
+	ID      string
+	Default string
+}
+
+var (
+	msgNotPermitted          = localizedMessage{ID: "docup.create.not_permitted", Default: "You are not permitted to create documentation issues"}
+	msgPostNotFound          = localizedMessage{ID: "docup.create.post_not_found", Default: postNotFoundMessage}
+	msgCannotReadPost        = localizedMessage{ID: "docup.create.cannot_read_post", Default: "You do not have permission to read this post"}
+	msgMisconfiguredRepo     = localizedMessage{ID: "docup.create.misconfigured_repository", Default: "The repository for this documentation type is misconfigured"}
+	msgRecentlySubmitted     = localizedMessage{ID: "docup.create.recently_submitted", Default: "This post was already marked for documentation moments ago"}
+	msgContentBlocked        = localizedMessage{ID: "docup.create.content_blocked", Default: "Your content was blocked because it matches a pattern that is not allowed in issues, such as a secret. Please remove it and try again"}
+	msgConfirmationCreated   = localizedMessage{ID: "docup.confirmation.created", Default: "Marked [this post](%s) for documentation [here](%s)."}
+	msgConfirmationExisting  = localizedMessage{ID: "docup.confirmation.existing", Default: "Marked [this post](%s) for documentation by updating an [existing issue](%s)."}
+	msgConfirmationOpenIssue = localizedMessage{ID: "docup.confirmation.open_issue", Default: "Open Issue"}
+)
+
+// translations maps locales to message IDs to translated messages.
+type translations map[string]map[string]string
+
+// loadTranslations reads the translation files in the given directory. A missing directory
+// yields no translations.
+func loadTranslations(dir string) (translations, error) {
+	loaded := translations{}
+
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return loaded, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read translations")
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read translations from %s", file.Name())
+		}
+
+		messages := map[string]string{}
+		if err := json.Unmarshal(contents, &messages); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode translations from %s", file.Name())
+		}
+		loaded[strings.ToLower(strings.TrimSuffix(file.Name(), ".json"))] = messages
+	}
+
+	return loaded, nil
+}
+
+// get returns the translation of the message with the given ID for the locale, falling back to
+// the locale's language, such as pt for pt-BR, when there is no translation for the region.
+func (t translations) get(locale, id string) (string, bool) {
+	locale = strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	if translated, ok := t[locale][id]; ok {
+		return translated, true
+	}
+	if i := strings.Index(locale, "-"); i != -1 {
+		if translated, ok := t[locale[:i]][id]; ok {
+			return translated, true
+		}
+	}
+	return "", false
+}
+
+// localize returns the message translated to the given locale and formatted with args, or the
+// English message if it has not been translated.
+func (p *Plugin) localize(locale string, message localizedMessage, args ...interface{}) string {
+	text, ok := p.translations.get(locale, message.ID)
+	if !ok {
+		text = message.Default
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// loadBundleTranslations loads the translations shipped in the plugin bundle.
+func (p *Plugin) loadBundleTranslations() error {
+	bundlePath, err := p.API.GetBundlePath()
+	if err != nil {
+		return errors.Wrap(err, "failed to get bundle path")
+	}
+
+	loaded, err := loadTranslations(filepath.Join(bundlePath, translationsDir))
+	if err != nil {
+		return err
+	}
+	p.translations = loaded
+	return nil
+}
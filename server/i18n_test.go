@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalize(t *testing.T) {
+	loaded, err := loadTranslations(filepath.Join("..", translationsDir))
+	require.NoError(t, err)
+
+	plugin := Plugin{translations: loaded}
+
+	for name, tc := range map[string]struct {
+		Locale   string
+		Expected string
+	}{
+		"english": {
+			Locale:   "en",
+			Expected: "Marked [this post](https://mattermost.example.com/_redirect/pl/post1) for documentation [here](https://github.com/owner/repo/issues/1).",
+		},
+		"translated": {
+			Locale:   "es",
+			Expected: "Se marcó [esta publicación](https://mattermost.example.com/_redirect/pl/post1) para documentación [aquí](https://github.com/owner/repo/issues/1).",
+		},
+		"regional locale": {
+			Locale:   "es-AR",
+			Expected: "Se marcó [esta publicación](https://mattermost.example.com/_redirect/pl/post1) para documentación [aquí](https://github.com/owner/repo/issues/1).",
+		},
+		"untranslated locale": {
+			Locale:   "zh-CN",
+			Expected: "Marked [this post](https://mattermost.example.com/_redirect/pl/post1) for documentation [here](https://github.com/owner/repo/issues/1).",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, plugin.localize(tc.Locale, msgConfirmationCreated, "https://mattermost.example.com/_redirect/pl/post1", "https://github.com/owner/repo/issues/1"))
+		})
+	}
+}
+
+func TestTranslationsAreComplete(t *testing.T) {
+	loaded, err := loadTranslations(filepath.Join("..", translationsDir))
+	require.NoError(t, err)
+
+	for _, message := range []localizedMessage{
+		msgNotPermitted,
+		msgPostNotFound,
+		msgCannotReadPost,
+		msgMisconfiguredRepo,
+		msgRecentlySubmitted,
+		msgConfirmationCreated,
+		msgConfirmationExisting,
+		msgConfirmationOpenIssue,
+	} {
+		assert.Equal(t, message.Default, loaded["en"][message.ID], message.ID)
+		for locale, messages := range loaded {
+			assert.Contains(t, messages, message.ID, locale)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// idempotencyKeyHeader carries a key chosen by the client, so that retrying a request to
+	// create an issue with the same key returns the original result instead of creating another
+	// issue.
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotentReplayedHeader is set on responses replaying the result of an earlier request.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength bounds the length of idempotency keys.
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyKeyPrefix prefixes the KV store keys of idempotent results, which are followed by
+	// a hash of the requesting user's ID and the idempotency key.
+	idempotencyKeyPrefix = "idempotency_"
+
+	// defaultIdempotencyKeyExpirySeconds is how long idempotent results are kept when
+	// IdempotencyKeyExpirySeconds is not configured.
+	defaultIdempotencyKeyExpirySeconds = 24 * 60 * 60
+)
+
+// idempotentResult records the response to a request made with an idempotency key.
+type idempotentResult struct {
+	Status   int                `json:"status"`
+	Response *CreateAPIResponse `json:"response"`
+
+	// InProgress is set while the request is still being handled, in which case there is no
+	// response yet.
+	InProgress bool `json:"in_progress,omitempty"`
+}
+
+// idempotentResultKey returns the KV store key of the result of the request made by the given
+// user with the given idempotency key. Keys are scoped to users, so that one user cannot replay
+// the results of another.
+func idempotentResultKey(userID, idempotencyKey string) string {
+	hash := sha256.Sum256([]byte(userID + "/" + idempotencyKey))
+	return idempotencyKeyPrefix + hex.EncodeToString(hash[:])[:model.KEY_VALUE_KEY_MAX_RUNES-len(idempotencyKeyPrefix)]
+}
+
+// saveIdempotentResult stores the result of the request made by the given user with the given
+// idempotency key, expiring once the configured window has passed.
+func (p *Plugin) saveIdempotentResult(userID, idempotencyKey string, result *idempotentResult) error {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode idempotent result")
+	}
+
+	if appErr := p.API.KVSetWithExpiry(idempotentResultKey(userID, idempotencyKey), value, p.getConfiguration().getIdempotencyKeyExpirySeconds()); appErr != nil {
+		return errors.Wrap(appErr, "failed to save idempotent result")
+	}
+	return nil
+}
+
+// reserveIdempotencyKey records that the request made by the given user with the given
+// idempotency key is in progress, unless a result was already recorded for it, in which case that
+// result is returned instead. The check and the reservation are made under idempotencyLock so that
+// concurrent retries cannot both create an issue. The reservation expires once the request would
+// have timed out, so that a request interrupted by a restart does not block its retries.
+func (p *Plugin) reserveIdempotencyKey(userID, idempotencyKey string) (*idempotentResult, bool, error) {
+	p.idempotencyLock.Lock()
+	defer p.idempotencyLock.Unlock()
+
+	result, err := p.getIdempotentResult(userID, idempotencyKey)
+	if err != nil || result != nil {
+		return result, false, err
+	}
+
+	value, err := json.Marshal(&idempotentResult{InProgress: true})
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to encode idempotent result")
+	}
+
+	expiry := int64(math.Ceil(p.getConfiguration().getCreateTimeout().Seconds()))
+	if appErr := p.API.KVSetWithExpiry(idempotentResultKey(userID, idempotencyKey), value, expiry); appErr != nil {
+		return nil, false, errors.Wrap(appErr, "failed to reserve idempotency key")
+	}
+	return nil, true, nil
+}
+
+// releaseIdempotencyKey removes the reservation made by reserveIdempotencyKey, so that the
+// request can be retried with the same idempotency key when no result is recorded for it.
+func (p *Plugin) releaseIdempotencyKey(userID, idempotencyKey string) error {
+	if appErr := p.API.KVDelete(idempotentResultKey(userID, idempotencyKey)); appErr != nil {
+		return errors.Wrap(appErr, "failed to release idempotency key")
+	}
+	return nil
+}
+
+// getIdempotentResult returns the result of the request made by the given user with the given
+// idempotency key, or nil if there is none or it has expired.
+func (p *Plugin) getIdempotentResult(userID, idempotencyKey string) (*idempotentResult, error) {
+	value, appErr := p.API.KVGet(idempotentResultKey(userID, idempotencyKey))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to get idempotent result")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var result *idempotentResult
+	if err := json.Unmarshal(value, &result); err != nil {
+		return nil, errors.Wrap(err, "failed to decode idempotent result")
+	}
+	return result, nil
+}
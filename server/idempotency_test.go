@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotentResultKey(t *testing.T) {
+	key := idempotentResultKey("user1", strings.Repeat("k", maxIdempotencyKeyLength))
+	assert.Len(t, key, model.KEY_VALUE_KEY_MAX_RUNES)
+	assert.True(t, strings.HasPrefix(key, idempotencyKeyPrefix))
+	assert.NotEqual(t, key, idempotentResultKey("user2", strings.Repeat("k", maxIdempotencyKeyLength)))
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	assert := assert.New(t)
+
+	store := map[string][]byte{}
+	resultKey := idempotentResultKey("user1", "retry-1")
+
+	api := &plugintest.API{}
+	api.On("GetUser", "user1").Return(&model.User{Id: "user1", Username: "user1"}, nil)
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: NewString("https://mattermost.example.com")}})
+	api.On("GetPost", "post1").Return(&model.Post{Id: "post1", ChannelId: "channel1", Message: "message"}, nil)
+	api.On("HasPermissionToChannel", "user1", "channel1", model.PERMISSION_READ_CHANNEL).Return(true)
+	api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", DisplayName: "Channel"}, nil)
+	api.On("GetTeam", "team1").Return(&model.Team{Id: "team1", DisplayName: "Team"}, nil)
+	api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithExpiry", resultKey, mock.Anything, int64(60)).Return(nil).Run(func(args mock.Arguments) {
+		store[args.String(0)] = args.Get(1).([]byte)
+	}).Once()
+	api.On("KVSetWithExpiry", resultKey, mock.Anything, int64(600)).Return(nil).Run(func(args mock.Arguments) {
+		store[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		return store[key]
+	}, nil)
+	api.On("AddReaction", mock.Anything).Return(&model.Reaction{}, nil)
+	defer api.AssertExpectations(t)
+
+	issueCreator := &fakeIssueCreator{}
+	plugin := Plugin{botUserID: "bot1", issueCreator: issueCreator}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{AdminRepository: "owner/repo", IdempotencyKeyExpirySeconds: "600"})
+
+	for i, expectedReplayed := range []string{"", "true"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/create", bytes.NewBufferString(`{"type":"admin","title":"title","body":"message","post_id":"post1"}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Mattermost-User-ID", "user1")
+		r.Header.Set(idempotencyKeyHeader, "retry-1")
+
+		plugin.ServeHTTP(nil, w, r)
+
+		result := w.Result()
+		assert.Equal(http.StatusCreated, result.StatusCode, "request %d", i)
+		assert.Equal(expectedReplayed, result.Header.Get(idempotentReplayedHeader), "request %d", i)
+
+		var response CreateAPIResponse
+		assert.Nil(json.NewDecoder(result.Body).Decode(&response))
+		assert.Equal("https://github.com/owner/repo/issues/1", response.IssueURL)
+		assert.Equal(1, response.IssueNumber)
+	}
+
+	assert.Len(issueCreator.requests, 1, "the retry should not create another issue")
+}
+
+func TestCreateIdempotencyKeyConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	var storeLock sync.Mutex
+	store := map[string][]byte{}
+	resultKey := idempotentResultKey("user1", "retry-1")
+
+	api := &plugintest.API{}
+	api.On("GetUser", "user1").Return(&model.User{Id: "user1", Username: "user1"}, nil)
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: NewString("https://mattermost.example.com")}})
+	api.On("GetPost", "post1").Return(&model.Post{Id: "post1", ChannelId: "channel1", Message: "message"}, nil)
+	api.On("HasPermissionToChannel", "user1", "channel1", model.PERMISSION_READ_CHANNEL).Return(true)
+	api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", DisplayName: "Channel"}, nil)
+	api.On("GetTeam", "team1").Return(&model.Team{Id: "team1", DisplayName: "Team"}, nil)
+	api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil).Once()
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithExpiry", resultKey, mock.Anything, mock.AnythingOfType("int64")).Return(nil).Run(func(args mock.Arguments) {
+		storeLock.Lock()
+		defer storeLock.Unlock()
+		store[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		storeLock.Lock()
+		defer storeLock.Unlock()
+		return store[key]
+	}, nil)
+	api.On("AddReaction", mock.Anything).Return(&model.Reaction{}, nil)
+	defer api.AssertExpectations(t)
+
+	creating := make(chan struct{})
+	release := make(chan struct{})
+	issuesCreated := 0
+	plugin := Plugin{botUserID: "bot1"}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{AdminRepository: "owner/repo"})
+	plugin.github = newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+		issuesCreated++
+		close(creating)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number": 1, "html_url": "https://github.com/owner/repo/issues/1"}`))
+	})
+
+	submit := func() *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/create", bytes.NewBufferString(`{"type":"admin","title":"title","body":"message","post_id":"post1"}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Mattermost-User-ID", "user1")
+		r.Header.Set(idempotencyKeyHeader, "retry-1")
+		plugin.ServeHTTP(nil, w, r)
+		return w.Result()
+	}
+
+	first := make(chan *http.Response)
+	go func() {
+		first <- submit()
+	}()
+
+	// The retry arrives while the first request is still filing the issue.
+	<-creating
+	result := submit()
+	assert.Equal(http.StatusConflict, result.StatusCode)
+	var response ErrorAPIResponse
+	assert.Nil(json.NewDecoder(result.Body).Decode(&response))
+	assert.Equal("A request with this Idempotency-Key is already in progress", response.Error)
+	close(release)
+
+	assert.Equal(http.StatusCreated, (<-first).StatusCode)
+	assert.Equal(1, issuesCreated)
+
+	result = submit()
+	assert.Equal(http.StatusCreated, result.StatusCode)
+	assert.Equal("true", result.Header.Get(idempotentReplayedHeader))
+}
+
+func TestCreateIdempotencyKeyReleasedOnFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	store := map[string][]byte{}
+	resultKey := idempotentResultKey("user1", "retry-1")
+
+	api := &plugintest.API{}
+	api.On("GetUser", "user1").Return(&model.User{Id: "user1", Username: "user1"}, nil)
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: NewString("https://mattermost.example.com")}})
+	api.On("GetPost", "post1").Return(&model.Post{Id: "post1", ChannelId: "channel1", Message: "message"}, nil)
+	api.On("HasPermissionToChannel", "user1", "channel1", model.PERMISSION_READ_CHANNEL).Return(true)
+	api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", DisplayName: "Channel"}, nil)
+	api.On("GetTeam", "team1").Return(&model.Team{Id: "team1", DisplayName: "Team"}, nil)
+	api.On("LogError", logArguments(5)...).Return()
+	api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil).Once()
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("KVSetWithExpiry", resultKey, mock.Anything, mock.AnythingOfType("int64")).Return(nil).Run(func(args mock.Arguments) {
+		store[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVDelete", resultKey).Return(nil).Run(func(args mock.Arguments) {
+		delete(store, args.String(0))
+	}).Once()
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		return store[key]
+	}, nil)
+	api.On("AddReaction", mock.Anything).Return(&model.Reaction{}, nil)
+	defer api.AssertExpectations(t)
+
+	status := http.StatusBadRequest
+	plugin := Plugin{botUserID: "bot1"}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{AdminRepository: "owner/repo"})
+	plugin.github = newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"number": 1, "html_url": "https://github.com/owner/repo/issues/1"}`))
+	})
+
+	submit := func() *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/create", bytes.NewBufferString(`{"type":"admin","title":"title","body":"message","post_id":"post1"}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Mattermost-User-ID", "user1")
+		r.Header.Set(idempotencyKeyHeader, "retry-1")
+		plugin.ServeHTTP(nil, w, r)
+		return w.Result()
+	}
+
+	assert.Equal(http.StatusInternalServerError, submit().StatusCode)
+	assert.NotContains(store, resultKey)
+
+	status = http.StatusCreated
+	assert.Equal(http.StatusCreated, submit().StatusCode)
+}
+
+func TestCreateIdempotencyKeyTooLong(t *testing.T) {
+	assert := assert.New(t)
+
+	plugin := Plugin{}
+	plugin.setConfiguration(&configuration{AdminRepository: "owner/repo"})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/create", bytes.NewBufferString(`{"type":"admin","title":"title","body":"message","post_id":"post1"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Mattermost-User-ID", "user1")
+	r.Header.Set(idempotencyKeyHeader, strings.Repeat("k", maxIdempotencyKeyLength+1))
+
+	plugin.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	assert.Equal(http.StatusBadRequest, result.StatusCode)
+	var response ErrorAPIResponse
+	assert.Nil(json.NewDecoder(result.Body).Decode(&response))
+	assert.Equal("Idempotency-Key must be at most 255 characters", response.Error)
+}
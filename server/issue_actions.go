@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// IssueActionAPIRequest asks to comment on or reopen an issue from the thread of a post. The
+// repository is resolved from the post's channel and Type as for slash commands.
+type IssueActionAPIRequest struct {
+	PostID      string `json:"post_id"`
+	Type        string `json:"type"`
+	IssueNumber int    `json:"issue_number"`
+	Message     string `json:"message"`
+}
+
+// IssueActionAPIResponse is returned once an issue has been commented on or reopened.
+type IssueActionAPIResponse struct {
+	IssueURL    string `json:"issue_url"`
+	IssueNumber int    `json:"issue_number"`
+}
+
+// issueAction holds what is needed to act on the issue referenced by an IssueActionAPIRequest.
+type issueAction struct {
+	userID    string
+	request   *IssueActionAPIRequest
+	user      *model.User
+	docPost   *model.Post
+	permalink string // empty when IncludePermalink is disabled
+	owner     string
+	repo      string
+	mapping   *issueMapping
+	client    *github.Client
+}
+
+// handleComment comments on an existing issue on behalf of the requesting user.
+func (p *Plugin) handleComment(w http.ResponseWriter, r *http.Request) {
+	action, ok := p.prepareIssueAction(w, r, true)
+	if !ok {
+		return
+	}
+
+	config := p.getConfiguration()
+	if config.isBlocked(action.request.Message) {
+		writeJSONError(w, http.StatusUnprocessableEntity, p.localize(action.user.Locale, msgContentBlocked))
+		return
+	}
+
+	message, _, _ := config.preparePostBody(action.request.Message)
+	attribution := fmt.Sprintf("_Commented by Mattermost user `%s`._", action.user.Username)
+	if action.permalink != "" {
+		attribution = fmt.Sprintf("_Commented by Mattermost user `%s` from [this post](%s)._", action.user.Username, action.permalink)
+	}
+	comment := &github.IssueComment{
+		Body: NewString(message + "\n\n" + attribution),
+	}
+
+	ctx, cancel := p.githubContext()
+	started := time.Now()
+	createdComment, _, err := action.client.Issues.CreateComment(ctx, action.owner, action.repo, action.request.IssueNumber, comment)
+	cancel()
+	if err != nil {
+		p.respondIssueActionError(w, action, err, started, "Error commenting on GitHub issue")
+		return
+	}
+
+	p.respondIssueAction(w, action, createdComment.GetHTMLURL(), "Commented on [#%d](%s).")
+}
+
+// handleReopen reopens an existing issue on behalf of the requesting user.
+func (p *Plugin) handleReopen(w http.ResponseWriter, r *http.Request) {
+	action, ok := p.prepareIssueAction(w, r, false)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := p.githubContext()
+	started := time.Now()
+	issue, _, err := action.client.Issues.Edit(ctx, action.owner, action.repo, action.request.IssueNumber, &github.IssueRequest{
+		State: NewString("open"),
+	})
+	cancel()
+	if err != nil {
+		p.respondIssueActionError(w, action, err, started, "Error reopening GitHub issue")
+		return
+	}
+
+	// Closing the reopened issue again is reported as usual, unless it is again closed in error.
+	if action.mapping.ClosedInError {
+		action.mapping.ClosedInError = false
+		if err := p.saveIssueMapping(action.owner+"/"+action.repo, action.request.IssueNumber, action.mapping); err != nil {
+			p.API.LogWarn("Unable to save issue mapping", "user_id", action.userID, "repo", action.owner+"/"+action.repo, "issue", action.request.IssueNumber, "error", err.Error())
+		}
+	}
+
+	p.respondIssueAction(w, action, issue.GetHTMLURL(), "Reopened [#%d](%s) for documentation.")
+}
+
+// CloseIssueAPIRequest asks to close an issue created in error. Reason, if any, is commented on
+// the issue before it is closed.
+type CloseIssueAPIRequest struct {
+	Repository  string `json:"repository"`
+	IssueNumber int    `json:"issue_number"`
+	Reason      string `json:"reason"`
+}
+
+// handleClose closes an issue created by the plugin in error on behalf of the requesting user,
+// who must be able to read the channel of the post the issue was created for.
+func (p *Plugin) handleClose(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		writeJSONError(w, http.StatusUnauthorized, "Not authorized")
+		return
+	}
+
+	var request *CloseIssueAPIRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request == nil || request.Repository == "" || request.IssueNumber < 1 {
+		writeJSONError(w, http.StatusBadRequest, "repository and issue_number are required")
+		return
+	}
+
+	if !p.getConfiguration().isGitHub() {
+		writeJSONError(w, http.StatusBadRequest, "Only available when issues are filed on GitHub")
+		return
+	}
+
+	owner, repo, err := splitOwnerAndRepo(request.Repository)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	logFields := []interface{}{"user_id", userID, "repo", owner + "/" + repo, "issue", request.IssueNumber}
+
+	mapping, err := p.getIssueMapping(owner+"/"+repo, request.IssueNumber)
+	if err != nil {
+		p.API.LogError("Unable to get issue mapping", withLogFields(logFields, "error", err.Error())...)
+		writeJSONError(w, http.StatusInternalServerError, "Unable to get issue mapping")
+		return
+	}
+	if mapping == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Issue #%d of %s/%s was not created for a Mattermost post", request.IssueNumber, owner, repo))
+		return
+	}
+	logFields = withLogFields(logFields, "post_id", mapping.PostID)
+
+	if !p.API.HasPermissionToChannel(userID, mapping.ChannelID, model.PERMISSION_READ_CHANNEL) {
+		writeJSONError(w, http.StatusForbidden, "You do not have permission to read the post this issue was created for")
+		return
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		p.API.LogError("Unable to get user", withLogFields(logFields, "error", appErr.Error())...)
+		writeJSONError(w, http.StatusInternalServerError, "Unable to get user")
+		return
+	}
+
+	config := p.getConfiguration()
+	if config.isBlocked(request.Reason) {
+		writeJSONError(w, http.StatusUnprocessableEntity, p.localize(user.Locale, msgContentBlocked))
+		return
+	}
+
+	client := p.getGitHubClientForUser(userID)
+	started := time.Now()
+
+	if request.Reason != "" {
+		reason, _, _ := config.preparePostBody(request.Reason)
+		ctx, cancel := p.githubContext()
+		_, _, err = client.Issues.CreateComment(ctx, owner, repo, request.IssueNumber, &github.IssueComment{
+			Body: NewString(fmt.Sprintf("%s\n\n_Closed as created in error by Mattermost user `%s`._", reason, user.Username)),
+		})
+		cancel()
+		if err != nil {
+			p.API.LogError("Error commenting on GitHub issue", withLogFields(logFields, "error", err.Error())...)
+			writeIssueError(w, p.convertGitHubError(err, started, userID, mapping.ChannelID, mapping.RootID, "Error commenting on GitHub issue", logFields))
+			return
+		}
+	}
+
+	// The mapping is marked before closing the issue, so that the webhook for the close, which
+	// may arrive before GitHub responds, does not report the documentation as completed.
+	mapping.ClosedInError = true
+	if err := p.saveIssueMapping(owner+"/"+repo, request.IssueNumber, mapping); err != nil {
+		p.API.LogWarn("Unable to save issue mapping", withLogFields(logFields, "error", err.Error())...)
+	}
+
+	ctx, cancel := p.githubContext()
+	issue, _, err := client.Issues.Edit(ctx, owner, repo, request.IssueNumber, &github.IssueRequest{
+		State: NewString("closed"),
+	})
+	cancel()
+	if err != nil {
+		p.API.LogError("Error closing GitHub issue", withLogFields(logFields, "error", err.Error())...)
+		mapping.ClosedInError = false
+		if saveErr := p.saveIssueMapping(owner+"/"+repo, request.IssueNumber, mapping); saveErr != nil {
+			p.API.LogWarn("Unable to save issue mapping", withLogFields(logFields, "error", saveErr.Error())...)
+		}
+		writeIssueError(w, p.convertGitHubError(err, started, userID, mapping.ChannelID, mapping.RootID, "Error closing GitHub issue", logFields))
+		return
+	}
+
+	postUserID := p.botUserID
+	if postUserID == "" {
+		postUserID = userID
+	}
+
+	if _, appErr := p.API.CreatePost(&model.Post{
+		UserId:    postUserID,
+		ChannelId: mapping.ChannelID,
+		RootId:    mapping.RootID,
+		ParentId:  mapping.PostID,
+		Message:   fmt.Sprintf("Closed [#%d](%s) as created in error.", request.IssueNumber, issue.GetHTMLURL()),
+	}); appErr != nil {
+		p.API.LogError("Unable to create post", withLogFields(logFields, "error", appErr.Error())...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&IssueActionAPIResponse{IssueURL: issue.GetHTMLURL(), IssueNumber: request.IssueNumber}); err != nil {
+		p.API.LogError("Unable to encode JSON", withLogFields(logFields, "error", err.Error())...)
+	}
+}
+
+// prepareIssueAction decodes and validates an IssueActionAPIRequest, checking that the requesting
+// user can read the post, resolving the issue's repository and checking that the issue was
+// created by the plugin for a post the user can read. If ok is false, an error has already been
+// written to w.
+func (p *Plugin) prepareIssueAction(w http.ResponseWriter, r *http.Request, requireMessage bool) (*issueAction, bool) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return nil, false
+	}
+
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		writeJSONError(w, http.StatusUnauthorized, "Not authorized")
+		return nil, false
+	}
+
+	var request *IssueActionAPIRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request == nil || request.PostID == "" || request.IssueNumber < 1 {
+		writeJSONError(w, http.StatusBadRequest, "post_id and issue_number are required")
+		return nil, false
+	}
+	if requireMessage && request.Message == "" {
+		writeJSONError(w, http.StatusBadRequest, "message is required")
+		return nil, false
+	}
+
+	config := p.getConfiguration()
+	if !config.isGitHub() {
+		writeJSONError(w, http.StatusBadRequest, "Only available when issues are filed on GitHub")
+		return nil, false
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		p.API.LogError("Unable to get user", "user_id", userID, "error", appErr.Error())
+		writeJSONError(w, http.StatusInternalServerError, "Unable to get user")
+		return nil, false
+	}
+
+	docPost, appErr := p.API.GetPost(request.PostID)
+	if appErr != nil && appErr.StatusCode == http.StatusNotFound {
+		writeJSONError(w, http.StatusNotFound, postNotFoundMessage)
+		return nil, false
+	}
+	if appErr != nil {
+		p.API.LogError("Unable to get post", "user_id", userID, "post_id", request.PostID, "error", appErr.Error())
+		writeJSONError(w, http.StatusInternalServerError, "Unable to get post")
+		return nil, false
+	}
+
+	if !p.API.HasPermissionToChannel(userID, docPost.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		writeJSONError(w, http.StatusForbidden, "You do not have permission to read this post")
+		return nil, false
+	}
+
+	owner, repo, message := p.getCommandRepository(config, docPost.ChannelId, request.Type)
+	if message != "" {
+		writeJSONError(w, http.StatusBadRequest, message)
+		return nil, false
+	}
+
+	// Only issues created by the plugin can be acted on, and only by users who can read the post
+	// they were created for, as the action may be taken with the shared credentials.
+	mapping, err := p.getIssueMapping(owner+"/"+repo, request.IssueNumber)
+	if err != nil {
+		p.API.LogError("Unable to get issue mapping", "user_id", userID, "repo", owner+"/"+repo, "issue", request.IssueNumber, "error", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, "Unable to get issue mapping")
+		return nil, false
+	}
+	if mapping == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Issue #%d of %s/%s was not created for a Mattermost post", request.IssueNumber, owner, repo))
+		return nil, false
+	}
+	if mapping.ChannelID != docPost.ChannelId && !p.API.HasPermissionToChannel(userID, mapping.ChannelID, model.PERMISSION_READ_CHANNEL) {
+		writeJSONError(w, http.StatusForbidden, "You do not have permission to read the post this issue was created for")
+		return nil, false
+	}
+
+	permalink := ""
+	if config.includePermalink() {
+		siteURL, err := p.getSiteURL()
+		if err != nil {
+			p.API.LogError("Unable to get site URL", "user_id", userID, "error", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, "Unable to get site URL")
+			return nil, false
+		}
+
+		postURL, err := url.Parse(siteURL)
+		if err != nil {
+			p.API.LogError("Unable to parse site URL", "user_id", userID, "error", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, "Unable to parse site URL")
+			return nil, false
+		}
+		postURL.Path = path.Join(postURL.Path, "_redirect", "pl", docPost.Id)
+		permalink = postURL.String()
+	}
+
+	return &issueAction{
+		userID:    userID,
+		request:   request,
+		user:      user,
+		docPost:   docPost,
+		permalink: permalink,
+		owner:     owner,
+		repo:      repo,
+		mapping:   mapping,
+		client:    p.getGitHubClientForUser(userID),
+	}, true
+}
+
+// respondIssueAction confirms a completed issue action in the thread of the post using the given
+// message format, which receives the issue number and URL, and writes the IssueActionAPIResponse.
+func (p *Plugin) respondIssueAction(w http.ResponseWriter, action *issueAction, issueURL, format string) {
+	number := action.request.IssueNumber
+
+	rootID := action.docPost.RootId
+	if rootID == "" {
+		rootID = action.docPost.Id
+	}
+
+	postUserID := p.botUserID
+	if postUserID == "" {
+		postUserID = action.userID
+	}
+
+	if _, appErr := p.API.CreatePost(&model.Post{
+		UserId:    postUserID,
+		ChannelId: action.docPost.ChannelId,
+		RootId:    rootID,
+		ParentId:  action.docPost.Id,
+		Message:   fmt.Sprintf(format, number, issueURL),
+	}); appErr != nil {
+		p.API.LogError("Unable to create post", "user_id", action.userID, "post_id", action.docPost.Id, "error", appErr.Error())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&IssueActionAPIResponse{IssueURL: issueURL, IssueNumber: number}); err != nil {
+		p.API.LogError("Unable to encode JSON", "user_id", action.userID, "error", err.Error())
+	}
+}
+
+// writeIssueError writes an error returned by convertGitHubError as a JSON error response.
+func writeIssueError(w http.ResponseWriter, err error) {
+	if issueErr, ok := err.(*issueError); ok {
+		writeJSONError(w, issueErr.status, issueErr.message)
+		return
+	}
+	writeJSONError(w, http.StatusInternalServerError, "Unable to reach GitHub, please try again later")
+}
+
+// respondIssueActionError writes the status of a failed GitHub call made for an issue action.
+func (p *Plugin) respondIssueActionError(w http.ResponseWriter, action *issueAction, err error, started time.Time, message string) {
+	logFields := []interface{}{"user_id", action.userID, "post_id", action.docPost.Id, "repo", action.owner + "/" + action.repo, "issue", action.request.IssueNumber}
+	p.API.LogError(message, withLogFields(logFields, "error", err.Error())...)
+
+	if errResponse, ok := err.(*github.ErrorResponse); ok && errResponse.Response != nil && errResponse.Response.StatusCode == http.StatusNotFound {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Issue #%d does not exist in %s/%s", action.request.IssueNumber, action.owner, action.repo))
+		return
+	}
+
+	rootID := action.docPost.RootId
+	if rootID == "" {
+		rootID = action.docPost.Id
+	}
+	writeIssueError(w, p.convertGitHubError(err, started, action.userID, action.docPost.ChannelId, rootID, message, logFields))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	providerGitHub = "github"
+	providerGitLab = "gitlab"
+	providerGitea  = "gitea"
+)
+
+// IssueCreator creates issues in a repository of an issue tracker. Requests and results are
+// expressed with the go-github types regardless of the backend.
+type IssueCreator interface {
+	CreateIssue(ctx context.Context, owner, repo string, req *github.IssueRequest) (*github.Issue, error)
+}
+
+// githubIssueCreator creates issues on GitHub or GitHub Enterprise.
+type githubIssueCreator struct {
+	client *github.Client
+}
+
+func (c *githubIssueCreator) CreateIssue(ctx context.Context, owner, repo string, req *github.IssueRequest) (*github.Issue, error) {
+	issue, _, err := c.client.Issues.Create(ctx, owner, repo, req)
+	return issue, err
+}
+
+// getIssueCreator returns the IssueCreator for the configured provider, creating GitHub issues
+// with the given client, unless one has been injected on the plugin.
+func (p *Plugin) getIssueCreator(client *github.Client) IssueCreator {
+	if p.issueCreator != nil {
+		return p.issueCreator
+	}
+
+	config := p.getConfiguration()
+	switch config.Provider {
+	case providerGitLab:
+		return newGitLabIssueCreator(config.GitLabURL, config.GitLabToken)
+	case providerGitea:
+		return newGiteaIssueCreator(config.GiteaURL, config.GiteaToken)
+	}
+
+	return &githubIssueCreator{client: client}
+}
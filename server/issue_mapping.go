@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// issueMappingKeyPrefix prefixes the KV store keys of issue mappings, which are followed by
+	// the lowercased owner/repo and the issue number, as in issue_owner/repo/42. Mappings never
+	// expire.
+	issueMappingKeyPrefix = "issue_"
+
+	// postIssueKeyPrefix prefixes the KV store keys of post issues, which are followed by the ID
+	// of the marked post.
+	postIssueKeyPrefix = "post_"
+
+	// threadIssueKeyPrefix prefixes the KV store keys of thread issues, which are followed by the
+	// ID of the thread's root post.
+	threadIssueKeyPrefix = "thread_"
+)
+
+// issueMapping records the post an issue was created for, so that follow-ups such as webhooks
+// can reply in its thread.
+type issueMapping struct {
+	ChannelID string `json:"channel_id"`
+	RootID    string `json:"root_id"`
+	PostID    string `json:"post_id"`
+
+	// ClosedInError is set when the issue was closed from Mattermost as created in error, so that
+	// closing it is not also reported as completed documentation.
+	ClosedInError bool `json:"closed_in_error,omitempty"`
+}
+
+// issueMappingKey returns the KV store key of the mapping for the given issue. Keys that would
+// exceed the KV store's limit are hashed.
+func issueMappingKey(ownerAndRepo string, number int) string {
+	key := fmt.Sprintf("%s%s/%d", issueMappingKeyPrefix, strings.ToLower(ownerAndRepo), number)
+	if len(key) <= model.KEY_VALUE_KEY_MAX_RUNES {
+		return key
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	return issueMappingKeyPrefix + hex.EncodeToString(hash[:])[:model.KEY_VALUE_KEY_MAX_RUNES-len(issueMappingKeyPrefix)]
+}
+
+// saveIssueMapping stores the mapping for the given issue.
+func (p *Plugin) saveIssueMapping(ownerAndRepo string, number int, mapping *issueMapping) error {
+	value, err := json.Marshal(mapping)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode issue mapping")
+	}
+
+	if appErr := p.API.KVSet(issueMappingKey(ownerAndRepo, number), value); appErr != nil {
+		return errors.Wrap(appErr, "failed to save issue mapping")
+	}
+	return nil
+}
+
+// getIssueMapping returns the mapping for the given issue, or nil if there is none.
+func (p *Plugin) getIssueMapping(ownerAndRepo string, number int) (*issueMapping, error) {
+	value, appErr := p.API.KVGet(issueMappingKey(ownerAndRepo, number))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to get issue mapping")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var mapping *issueMapping
+	if err := json.Unmarshal(value, &mapping); err != nil {
+		return nil, errors.Wrap(err, "failed to decode issue mapping")
+	}
+	return mapping, nil
+}
+
+// threadIssue records the issue created for a post in a thread, so that later replies in the
+// thread can be added to it.
+type threadIssue struct {
+	Repository  string `json:"repository"`
+	IssueNumber int    `json:"issue_number"`
+}
+
+// saveThreadIssue stores the issue created for a post in the thread with the given root post,
+// replacing any previous one.
+func (p *Plugin) saveThreadIssue(rootID string, issue *threadIssue) error {
+	value, err := json.Marshal(issue)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode thread issue")
+	}
+
+	if appErr := p.API.KVSet(threadIssueKeyPrefix+rootID, value); appErr != nil {
+		return errors.Wrap(appErr, "failed to save thread issue")
+	}
+	return nil
+}
+
+// getThreadIssue returns the issue created for a post in the thread with the given root post, or
+// nil if there is none.
+func (p *Plugin) getThreadIssue(rootID string) (*threadIssue, error) {
+	value, appErr := p.API.KVGet(threadIssueKeyPrefix + rootID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to get thread issue")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var issue *threadIssue
+	if err := json.Unmarshal(value, &issue); err != nil {
+		return nil, errors.Wrap(err, "failed to decode thread issue")
+	}
+	return issue, nil
+}
+
+// postIssue records the issue created for a marked post, so that marking it again can reuse it.
+type postIssue struct {
+	Repository  string `json:"repository"`
+	IssueNumber int    `json:"issue_number"`
+	IssueURL    string `json:"issue_url"`
+}
+
+// savePostIssue stores the issue created for the given post.
+func (p *Plugin) savePostIssue(postID string, issue *postIssue) error {
+	value, err := json.Marshal(issue)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode post issue")
+	}
+
+	if appErr := p.API.KVSet(postIssueKeyPrefix+postID, value); appErr != nil {
+		return errors.Wrap(appErr, "failed to save post issue")
+	}
+	return nil
+}
+
+// getPostIssue returns the issue created for the given post, or nil if there is none.
+func (p *Plugin) getPostIssue(postID string) (*postIssue, error) {
+	value, appErr := p.API.KVGet(postIssueKeyPrefix + postID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to get post issue")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var issue *postIssue
+	if err := json.Unmarshal(value, &issue); err != nil {
+		return nil, errors.Wrap(err, "failed to decode post issue")
+	}
+	return issue, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueMappingKey(t *testing.T) {
+	assert.Equal(t, "issue_owner/repo/1", issueMappingKey("Owner/Repo", 1))
+
+	key := issueMappingKey("a-very-long-organization-name/a-very-long-repository-name", 12345)
+	assert.Len(t, key, model.KEY_VALUE_KEY_MAX_RUNES)
+	assert.True(t, strings.HasPrefix(key, issueMappingKeyPrefix))
+}
+
+func TestIssueMapping(t *testing.T) {
+	store := map[string][]byte{}
+
+	api := &plugintest.API{}
+	api.On("KVSet", "issue_owner/repo/1", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		store[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		return store[key]
+	}, nil)
+
+	plugin := Plugin{}
+	plugin.SetAPI(api)
+
+	mapping := &issueMapping{ChannelID: "channel1", RootID: "post1", PostID: "post2"}
+	require.NoError(t, plugin.saveIssueMapping("owner/repo", 1, mapping))
+
+	saved, err := plugin.getIssueMapping("owner/repo", 1)
+	require.NoError(t, err)
+	assert.Equal(t, mapping, saved)
+
+	missing, err := plugin.getIssueMapping("owner/repo", 2)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	// issueTemplatePlaceholder marks where the rendered issue body is substituted into an issue
+	// template fetched from the repository. The body is appended to templates without it.
+	issueTemplatePlaceholder = "<!-- docup:body -->"
+
+	// issueTemplateCacheTTL is how long a fetched issue template is used before fetching it again.
+	issueTemplateCacheTTL = 10 * time.Minute
+)
+
+// issueTemplateCache caches issue templates fetched from repositories, keyed by owner/repo and
+// path.
+type issueTemplateCache struct {
+	lock      sync.Mutex
+	templates map[string]*cachedIssueTemplate
+}
+
+type cachedIssueTemplate struct {
+	content   string
+	fetchedAt time.Time
+}
+
+func (c *issueTemplateCache) get(key string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cached, ok := c.templates[key]
+	if !ok || time.Since(cached.fetchedAt) > issueTemplateCacheTTL {
+		return "", false
+	}
+	return cached.content, true
+}
+
+func (c *issueTemplateCache) set(key, content string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.templates == nil {
+		c.templates = map[string]*cachedIssueTemplate{}
+	}
+	c.templates[key] = &cachedIssueTemplate{content: content, fetchedAt: time.Now()}
+}
+
+// getIssueTemplate returns the contents of the issue template at the given path in the
+// repository, without any front matter.
+func (p *Plugin) getIssueTemplate(ctx context.Context, client *github.Client, owner, repo, templatePath string) (string, error) {
+	key := strings.ToLower(owner+"/"+repo) + ":" + templatePath
+	if content, ok := p.issueTemplates.get(key); ok {
+		return content, nil
+	}
+
+	reader, err := client.Repositories.DownloadContents(ctx, owner, repo, templatePath, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to download issue template")
+	}
+	defer reader.Close()
+
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read issue template")
+	}
+
+	content := stripFrontMatter(string(contents))
+	p.issueTemplates.set(key, content)
+	return content, nil
+}
+
+// applyIssueTemplate substitutes body into the issue template, or appends it to the template if
+// the template has no placeholder.
+func applyIssueTemplate(template, body string) string {
+	if strings.Contains(template, issueTemplatePlaceholder) {
+		return strings.Replace(template, issueTemplatePlaceholder, body, 1)
+	}
+	return strings.TrimRight(template, "\n") + "\n\n" + body
+}
+
+// stripFrontMatter removes the YAML front matter GitHub issue templates start with.
+func stripFrontMatter(template string) string {
+	normalized := strings.Replace(template, "\r\n", "\n", -1)
+	if !strings.HasPrefix(normalized, "---\n") {
+		return template
+	}
+
+	end := strings.Index(normalized[len("---\n"):], "\n---")
+	if end == -1 {
+		return template
+	}
+
+	rest := normalized[len("---\n")+end+len("\n---"):]
+	return strings.TrimLeft(rest, "\n")
+}
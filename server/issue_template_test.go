@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyIssueTemplate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Template string
+		Expected string
+	}{
+		"placeholder": {
+			Template: "## Request\n\n<!-- docup:body -->\n\n## Checklist\n- [ ] Written",
+			Expected: "## Request\n\nbody\n\n## Checklist\n- [ ] Written",
+		},
+		"no placeholder": {
+			Template: "## Checklist\n- [ ] Written\n",
+			Expected: "## Checklist\n- [ ] Written\n\nbody",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, applyIssueTemplate(tc.Template, "body"))
+		})
+	}
+}
+
+func TestStripFrontMatter(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Template string
+		Expected string
+	}{
+		"no front matter": {
+			Template: "## Request\n",
+			Expected: "## Request\n",
+		},
+		"front matter": {
+			Template: "---\nname: Documentation\nabout: Request documentation\n---\n\n## Request\n",
+			Expected: "## Request\n",
+		},
+		"windows line endings": {
+			Template: "---\r\nname: Documentation\r\n---\r\n## Request\r\n",
+			Expected: "## Request\n",
+		},
+		"unterminated front matter": {
+			Template: "---\nname: Documentation\n",
+			Expected: "---\nname: Documentation\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, stripFrontMatter(tc.Template))
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/github"
+)
+
+// setGitHubIssueType sets the native GitHub issue type of the issue with the given number. The
+// client library predates issue types, so the issue is updated with a raw request. Repositories
+// whose organization has no such issue type reject the update, in which case a label named after
+// the type is added instead. Failures are logged with logFields rather than returned, as the
+// issue has already been created.
+func (p *Plugin) setGitHubIssueType(ctx context.Context, client *github.Client, owner, repo string, number int, issueType string, logFields []interface{}) {
+	logFields = withLogFields(logFields, "issue", number, "issue_type", issueType)
+
+	request, err := client.NewRequest(http.MethodPatch, fmt.Sprintf("repos/%s/%s/issues/%d", owner, repo, number), map[string]string{"type": issueType})
+	if err == nil {
+		_, err = client.Do(ctx, request, nil)
+	}
+	if err == nil {
+		return
+	}
+	p.API.LogWarn("Unable to set GitHub issue type, adding a label instead", withLogFields(logFields, "error", err.Error())...)
+
+	if _, _, err := client.Issues.AddLabelsToIssue(ctx, owner, repo, number, []string{issueType}); err != nil {
+		p.API.LogWarn("Unable to add issue type label", withLogFields(logFields, "error", err.Error())...)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetGitHubIssueType(t *testing.T) {
+	for name, tc := range map[string]struct {
+		TypesEnabled  bool
+		ExpectedCalls []string
+	}{
+		"issue type set": {
+			TypesEnabled:  true,
+			ExpectedCalls: []string{"PATCH /repos/owner/repo/issues/1"},
+		},
+		"falls back to a label": {
+			ExpectedCalls: []string{"PATCH /repos/owner/repo/issues/1", "POST /repos/owner/repo/issues/1/labels"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			if !tc.TypesEnabled {
+				api.On("LogWarn", logArguments(4)...).Return()
+			}
+			defer api.AssertExpectations(t)
+
+			calls := []string{}
+			plugin := Plugin{}
+			plugin.SetAPI(api)
+			client := newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.Method+" "+r.URL.Path)
+				switch r.Method {
+				case http.MethodPatch:
+					var update map[string]string
+					assert.Nil(json.NewDecoder(r.Body).Decode(&update))
+					assert.Equal(map[string]string{"type": "Task"}, update)
+					if !tc.TypesEnabled {
+						w.WriteHeader(http.StatusUnprocessableEntity)
+						_, _ = w.Write([]byte(`{"message": "Validation Failed"}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"number": 1}`))
+				case http.MethodPost:
+					var labels []string
+					assert.Nil(json.NewDecoder(r.Body).Decode(&labels))
+					assert.Equal([]string{"Task"}, labels)
+					_, _ = w.Write([]byte(`[{"name": "Task"}]`))
+				}
+			})
+
+			plugin.setGitHubIssueType(context.Background(), client, "owner", "repo", 1, "Task", []interface{}{"user_id", "user1"})
+
+			assert.Equal(tc.ExpectedCalls, calls)
+		})
+	}
+}
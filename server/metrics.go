@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// metrics counts the outcomes of requests to mark posts for documentation since the plugin was
+// activated.
+type metrics struct {
+	lock         sync.Mutex
+	created      int64
+	deduplicated int64
+	failed       int64
+}
+
+// recordCreate counts the outcome of a call to createIssueFromPost. Dry runs are not counted.
+func (m *metrics) recordCreate(createResponse *CreateAPIResponse, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	switch {
+	case err != nil:
+		m.failed++
+	case createResponse.Preview != nil:
+	case createResponse.Existing:
+		m.deduplicated++
+	default:
+		m.created++
+	}
+}
+
+// write writes the counters in the Prometheus text exposition format.
+func (m *metrics) write(w io.Writer) error {
+	m.lock.Lock()
+	created, deduplicated, failed := m.created, m.deduplicated, m.failed
+	m.lock.Unlock()
+
+	_, err := fmt.Fprintf(w, "# HELP docup_issues_total Requests to mark posts for documentation by outcome.\n"+
+		"# TYPE docup_issues_total counter\n"+
+		"docup_issues_total{outcome=\"created\"} %d\n"+
+		"docup_issues_total{outcome=\"deduplicated\"} %d\n"+
+		"docup_issues_total{outcome=\"failed\"} %d\n",
+		created, deduplicated, failed)
+	return err
+}
+
+// handleMetrics serves the plugin's metrics to system admins.
+func (p *Plugin) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM) {
+		http.Error(w, "Only system admins can view metrics", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := p.metrics.write(w); err != nil {
+		p.API.LogError("Unable to write metrics", "user_id", userID, "error", err.Error())
+	}
+}
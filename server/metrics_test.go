@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", "admin1", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	api.On("HasPermissionTo", "user1", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+	defer api.AssertExpectations(t)
+
+	plugin := Plugin{}
+	plugin.SetAPI(api)
+
+	plugin.metrics.recordCreate(&CreateAPIResponse{IssueNumber: 1}, nil)
+	plugin.metrics.recordCreate(&CreateAPIResponse{IssueNumber: 2}, nil)
+	plugin.metrics.recordCreate(&CreateAPIResponse{IssueNumber: 1, Existing: true}, nil)
+	plugin.metrics.recordCreate(&CreateAPIResponse{Preview: &IssuePreview{}}, nil)
+	plugin.metrics.recordCreate(nil, errors.New("failed"))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Mattermost-User-ID", "user1")
+	plugin.ServeHTTP(nil, w, r)
+	assert.Equal(http.StatusForbidden, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Mattermost-User-ID", "admin1")
+	plugin.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	assert.Equal(http.StatusOK, result.StatusCode)
+	bodyBytes, err := ioutil.ReadAll(result.Body)
+	assert.Nil(err)
+	assert.Contains(string(bodyBytes), `docup_issues_total{outcome="created"} 2`)
+	assert.Contains(string(bodyBytes), `docup_issues_total{outcome="deduplicated"} 1`)
+	assert.Contains(string(bodyBytes), `docup_issues_total{outcome="failed"} 1`)
+}
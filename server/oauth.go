@@ -0,0 +1,276 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+
+	"github.com/google/go-github/github"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// userTokenKeyPrefix prefixes the KV store keys of the GitHub tokens of connected users, which
+	// are followed by the user ID. Tokens are encrypted with the configured EncryptionKey.
+	userTokenKeyPrefix = "token_"
+
+	// oauthStateKeyPrefix prefixes the KV store keys of pending OAuth states, which are followed
+	// by the state and hold the ID of the user connecting.
+	oauthStateKeyPrefix = "oauth_"
+
+	// oauthStateExpirySeconds bounds how long a user has to complete the OAuth flow.
+	oauthStateExpirySeconds = 10 * 60
+)
+
+// getOAuthConfig returns the OAuth configuration of the GitHub OAuth app used to connect users'
+// GitHub accounts, pointing at GitHub Enterprise when a base URL is configured.
+func (p *Plugin) getOAuthConfig() *oauth2.Config {
+	config := p.getConfiguration()
+
+	authBaseURL := "https://github.com/"
+	if config.GitHubBaseURL != "" {
+		if baseURL, err := url.Parse(config.GitHubBaseURL); err == nil {
+			authBaseURL = baseURL.Scheme + "://" + baseURL.Host + "/"
+		}
+	}
+
+	siteURL := ""
+	if serverConfig := p.API.GetConfig(); serverConfig.ServiceSettings.SiteURL != nil {
+		siteURL = strings.TrimSuffix(*serverConfig.ServiceSettings.SiteURL, "/")
+	}
+
+	return &oauth2.Config{
+		ClientID:     config.GitHubOAuthClientID,
+		ClientSecret: config.GitHubOAuthClientSecret,
+		Scopes:       []string{"repo"},
+		RedirectURL:  siteURL + "/plugins/" + manifest.ID + "/oauth/complete",
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  authBaseURL + "login/oauth/authorize",
+			TokenURL: authBaseURL + "login/oauth/access_token",
+		},
+	}
+}
+
+// handleOAuthConnect starts connecting the requesting user's GitHub account.
+func (p *Plugin) handleOAuthConnect(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !p.getConfiguration().isOAuthConfigured() {
+		http.Error(w, "Connecting GitHub accounts is not configured", http.StatusNotFound)
+		return
+	}
+
+	state := model.NewId()
+	if appErr := p.API.KVSetWithExpiry(oauthStateKeyPrefix+state, []byte(userID), oauthStateExpirySeconds); appErr != nil {
+		p.API.LogError("Unable to save OAuth state", "user_id", userID, "error", appErr.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, p.getOAuthConfig().AuthCodeURL(state, oauth2.AccessTypeOnline), http.StatusFound)
+}
+
+// handleOAuthComplete finishes connecting the requesting user's GitHub account, storing their
+// token for creating issues on their behalf.
+func (p *Plugin) handleOAuthComplete(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	state := r.URL.Query().Get("state")
+	if code == "" || state == "" {
+		http.Error(w, "code and state are required", http.StatusBadRequest)
+		return
+	}
+
+	stateUserID, appErr := p.API.KVGet(oauthStateKeyPrefix + state)
+	if appErr != nil {
+		p.API.LogError("Unable to get OAuth state", "user_id", userID, "error", appErr.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if string(stateUserID) != userID {
+		http.Error(w, "Invalid state", http.StatusBadRequest)
+		return
+	}
+	if appErr := p.API.KVDelete(oauthStateKeyPrefix + state); appErr != nil {
+		p.API.LogWarn("Unable to delete OAuth state", "user_id", userID, "error", appErr.Error())
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	ctx, err := githubHTTPContext(ctx, p.getConfiguration())
+	if err != nil {
+		p.API.LogError("Unable to configure GitHub proxy", "user_id", userID, "error", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	token, err := p.getOAuthConfig().Exchange(ctx, code)
+	if err != nil {
+		p.API.LogError("Unable to exchange OAuth code", "user_id", userID, "error", err.Error())
+		http.Error(w, "Unable to connect your GitHub account", http.StatusBadGateway)
+		return
+	}
+
+	if err := p.saveUserToken(userID, token); err != nil {
+		p.API.LogError("Unable to save GitHub token", "user_id", userID, "error", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	_, _ = w.Write([]byte("<html><body><p>Your GitHub account is connected to Doc Up. You can close this window.</p></body></html>"))
+}
+
+// saveUserToken stores the GitHub token of the given user.
+func (p *Plugin) saveUserToken(userID string, token *oauth2.Token) error {
+	value, err := json.Marshal(token)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode token")
+	}
+
+	encrypted, err := encrypt([]byte(p.getConfiguration().EncryptionKey), value)
+	if err != nil {
+		return err
+	}
+
+	if appErr := p.API.KVSet(userTokenKeyPrefix+userID, encrypted); appErr != nil {
+		return errors.Wrap(appErr, "failed to save token")
+	}
+	return nil
+}
+
+// getUserToken returns the GitHub token of the given user, or nil if they have not connected
+// their GitHub account.
+func (p *Plugin) getUserToken(userID string) (*oauth2.Token, error) {
+	encrypted, appErr := p.API.KVGet(userTokenKeyPrefix + userID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to get token")
+	}
+	if encrypted == nil {
+		return nil, nil
+	}
+
+	value, err := decrypt([]byte(p.getConfiguration().EncryptionKey), encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	var token *oauth2.Token
+	if err := json.Unmarshal(value, &token); err != nil {
+		return nil, errors.Wrap(err, "failed to decode token")
+	}
+	return token, nil
+}
+
+// getGitHubClientForUser returns a GitHub client authenticated as the given user when
+// PreferUserToken is set and they have connected their GitHub account, or else the client
+// authenticated with the shared API key.
+func (p *Plugin) getGitHubClientForUser(userID string) *github.Client {
+	if !p.getConfiguration().PreferUserToken {
+		return p.getGitHubClient()
+	}
+
+	client, err := p.getUserGitHubClient(userID)
+	if err != nil {
+		p.API.LogWarn("Unable to create GitHub client for user, using the shared API key", "user_id", userID, "error", err.Error())
+		return p.getGitHubClient()
+	}
+	if client == nil {
+		return p.getGitHubClient()
+	}
+	return client
+}
+
+// getUserGitHubClient returns a GitHub client authenticated as the given user, or nil if
+// connecting GitHub accounts is not configured or they have not connected theirs.
+func (p *Plugin) getUserGitHubClient(userID string) (*github.Client, error) {
+	config := p.getConfiguration()
+	if !config.isOAuthConfigured() {
+		return nil, nil
+	}
+
+	token, err := p.getUserToken(userID)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, nil
+	}
+
+	return newGitHubClientWithToken(token.AccessToken, config)
+}
+
+// encrypt seals plaintext with AES-GCM under a key derived from key.
+func encrypt(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return []byte(base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// decrypt opens ciphertext sealed by encrypt.
+func decrypt(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(ciphertext))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode ciphertext")
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt ciphertext")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) == 0 {
+		return nil, errors.New("EncryptionKey not configured")
+	}
+
+	hashedKey := sha256.Sum256(key)
+	block, err := aes.NewCipher(hashedKey[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+	return gcm, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	encrypted, err := encrypt([]byte("key"), []byte("token"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "token")
+
+	decrypted, err := decrypt([]byte("key"), encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "token", string(decrypted))
+
+	_, err = decrypt([]byte("other key"), encrypted)
+	assert.Error(t, err)
+
+	_, err = encrypt([]byte{}, []byte("token"))
+	assert.Error(t, err)
+}
+
+func TestGetGitHubClientForUser(t *testing.T) {
+	store := map[string][]byte{}
+
+	api := &plugintest.API{}
+	api.On("KVSet", "token_user1", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		store[args.String(0)] = args.Get(1).([]byte)
+	})
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		return store[key]
+	}, nil)
+
+	plugin := Plugin{}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{
+		GitHubAPIKey:            "shared",
+		PreferUserToken:         true,
+		GitHubOAuthClientID:     "id",
+		GitHubOAuthClientSecret: "secret",
+		EncryptionKey:           "key",
+	})
+	require.NoError(t, plugin.ensureGitHubClient(plugin.getConfiguration()))
+	sharedClient := plugin.getGitHubClient()
+
+	require.NoError(t, plugin.saveUserToken("user1", &oauth2.Token{AccessToken: "personal"}))
+
+	token, err := plugin.getUserToken("user1")
+	require.NoError(t, err)
+	assert.Equal(t, "personal", token.AccessToken)
+
+	assert.NotEqual(t, sharedClient, plugin.getGitHubClientForUser("user1"))
+	assert.Equal(t, sharedClient, plugin.getGitHubClientForUser("user2"))
+}
@@ -4,17 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"mime"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/oauth2"
 
 	"github.com/google/go-github/github"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/plugin"
+	"github.com/pkg/errors"
 )
 
 // Plugin implements the interface expected by the Mattermost server to communicate between the server and plugin processes.
@@ -28,7 +33,53 @@ type Plugin struct {
 	// setConfiguration for usage.
 	configuration *configuration
 
+	// githubLock synchronizes access to the GitHub client.
+	githubLock sync.RWMutex
+
+	// github is the active GitHub client. Consult getGitHubClient and ensureGitHubClient for usage.
 	github *github.Client
+
+	// issueCreator replaces the IssueCreator of the configured provider when set, letting tests
+	// observe the issues created. Consult getIssueCreator for usage.
+	issueCreator IssueCreator
+
+	// githubCredentials, githubBaseURL and githubProxyURL record the settings github was built
+	// with, so that it is only rebuilt when they change.
+	githubCredentials string
+	githubBaseURL     string
+	githubProxyURL    string
+
+	// ctx is cancelled when the plugin is deactivated, aborting in-flight GitHub requests.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// botUserID is the ID of the bot that authors confirmation posts. It is empty if the bot
+	// could not be created, in which case posts are authored by the requesting user.
+	botUserID string
+
+	// issueTemplates caches the issue templates fetched from repositories when TemplatePath is
+	// configured.
+	issueTemplates issueTemplateCache
+
+	// historyLock serializes updates to the histories of users, which are read and written back.
+	historyLock sync.Mutex
+
+	// submissionLock serializes reserving posts for the submit cooldown. Consult
+	// reserveSubmission for usage.
+	submissionLock sync.Mutex
+
+	// idempotencyLock serializes reserving idempotency keys. Consult reserveIdempotencyKey for
+	// usage.
+	idempotencyLock sync.Mutex
+
+	// metrics counts the outcomes of requests to mark posts for documentation.
+	metrics metrics
+
+	// translations holds the translations of user-facing messages loaded from the plugin bundle.
+	translations translations
+
+	// health caches the outcome of checking that GitHub is reachable for the health endpoint.
+	health healthCheck
 }
 
 func (p *Plugin) OnActivate() error {
@@ -37,140 +88,1476 @@ func (p *Plugin) OnActivate() error {
 		return err
 	}
 
-	ctx := context.Background()
+	if err := p.ensureGitHubClient(config); err != nil {
+		return err
+	}
+
+	if config.ConfirmationChannelID != "" {
+		if _, appErr := p.API.GetChannel(config.ConfirmationChannelID); appErr != nil {
+			return errors.Wrapf(appErr, "failed to find ConfirmationChannelID channel %s", config.ConfirmationChannelID)
+		}
+	}
+
+	if organization := config.getOrganization(); organization != "" && config.isGitHub() {
+		if err := p.validateOrganization(organization); err != nil {
+			return err
+		}
+	}
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	botUserID, err := p.Helpers.EnsureBot(&model.Bot{
+		Username:    "docup",
+		DisplayName: "Doc Up",
+		Description: "Created by the Doc Up plugin.",
+	})
+	if err != nil {
+		p.API.LogWarn("Unable to ensure bot user, posts will be authored by the requesting user err=" + err.Error())
+	}
+	p.botUserID = botUserID
+
+	if err := p.loadBundleTranslations(); err != nil {
+		p.API.LogWarn("Unable to load translations, messages will be in English err=" + err.Error())
+	}
+
+	if config.ValidateReposOnStartup && config.isGitHub() {
+		go p.validateRepositories(config)
+	}
+
+	if err := p.API.RegisterCommand(getCommand()); err != nil {
+		return errors.Wrap(err, "failed to register command")
+	}
+
+	return nil
+}
+
+// OnDeactivate aborts in-flight GitHub requests, unregisters the slash command and drops the
+// GitHub client. It is safe to call more than once.
+func (p *Plugin) OnDeactivate() error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	if err := p.API.UnregisterCommand("", commandTrigger); err != nil {
+		p.API.LogWarn("Unable to unregister command err=" + err.Error())
+	}
+
+	p.githubLock.Lock()
+	p.github = nil
+	p.githubCredentials = ""
+	p.githubBaseURL = ""
+	p.githubProxyURL = ""
+	p.githubLock.Unlock()
+
+	return nil
+}
+
+// validateRepositories logs a warning for each configured repository that cannot be fetched from
+// GitHub, surfacing misconfigured or inaccessible repositories before anyone files a request.
+func (p *Plugin) validateRepositories(config *configuration) {
+	client := p.getGitHubClient()
+	for _, ownerAndRepo := range config.getAllRepositories() {
+		owner, repo, err := splitOwnerAndRepo(ownerAndRepo)
+		if err != nil {
+			p.API.LogWarn("Bad configured repo: " + ownerAndRepo)
+			continue
+		}
+
+		ctx, cancel := p.githubContext()
+		_, resp, err := client.Repositories.Get(ctx, owner, repo)
+		cancel()
+		if err == nil {
+			continue
+		}
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			p.API.LogWarn("Configured repository " + ownerAndRepo + " does not exist or is not accessible with the configured GitHub API key")
+			continue
+		}
+		p.API.LogWarn("Unable to validate configured repository " + ownerAndRepo + " err=" + err.Error())
+	}
+}
+
+// validateOrganization checks that the given GitHub organization exists. Only a missing
+// organization is an error, so that GitHub being unreachable does not prevent activation.
+func (p *Plugin) validateOrganization(organization string) error {
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	_, resp, err := p.getGitHubClient().Organizations.Get(ctx, organization)
+	if err == nil {
+		return nil
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return errors.Errorf("Organization %s does not exist or is not accessible with the configured credentials", organization)
+	}
+	p.API.LogWarn("Unable to validate configured organization " + organization + " err=" + err.Error())
+	return nil
+}
+
+// pluginContext returns a context that is cancelled when the plugin is deactivated.
+func (p *Plugin) pluginContext() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+// newGitHubClient creates a GitHub client authenticated with the configured API key, or as the
+// configured GitHub App installation, pointing at GitHub Enterprise when a base URL is configured.
+func newGitHubClient(config *configuration) (*github.Client, error) {
+	if config.AuthMode != authModeApp {
+		return newGitHubClientWithToken(config.GitHubAPIKey, config)
+	}
+
+	ctx, err := githubHTTPContext(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := newAppInstallationTokenSource(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return newGitHubClientWithTokenSource(ctx, ts, config)
+}
+
+// newGitHubClientWithToken creates a GitHub client authenticated with the given token, pointing
+// at GitHub Enterprise when a base URL is configured and sending requests through the configured
+// proxy, if any.
+func newGitHubClientWithToken(token string, config *configuration) (*github.Client, error) {
+	ctx, err := githubHTTPContext(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
 	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: config.GitHubAPIKey},
+		&oauth2.Token{AccessToken: token},
 	)
+	return newGitHubClientWithTokenSource(ctx, ts, config)
+}
+
+// newGitHubClientWithTokenSource creates a GitHub client authenticated with tokens from ts, sending
+// requests with the HTTP client carried by ctx, if any.
+func newGitHubClientWithTokenSource(ctx context.Context, ts oauth2.TokenSource, config *configuration) (*github.Client, error) {
 	tc := oauth2.NewClient(ctx, ts)
 
-	p.github = github.NewClient(tc)
+	baseURL := config.GitHubBaseURL
+
+	if baseURL == "" {
+		return github.NewClient(tc), nil
+	}
+
+	client, err := github.NewEnterpriseClient(baseURL, getEnterpriseUploadURL(baseURL), tc)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create GitHub Enterprise client")
+	}
+	return client, nil
+}
+
+// githubHTTPContext returns ctx carrying the HTTP client used by oauth2 for requests to GitHub,
+// which sends them through the configured proxy. ctx is returned unchanged without a proxy.
+func githubHTTPContext(ctx context.Context, config *configuration) (context.Context, error) {
+	if config.GitHubProxyURL == "" {
+		return ctx, nil
+	}
+
+	proxyURL, err := url.Parse(config.GitHubProxyURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse GitHub proxy URL")
+	}
+
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyURL(proxyURL),
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}), nil
+}
+
+// getGitHubClient retrieves the active GitHub client under lock, making it safe to use
+// concurrently with configuration changes.
+func (p *Plugin) getGitHubClient() *github.Client {
+	p.githubLock.RLock()
+	defer p.githubLock.RUnlock()
+
+	return p.github
+}
+
+// ensureGitHubClient rebuilds the active GitHub client under lock if the credentials, base URL or
+// proxy URL in the given configuration differ from those the current client was built with.
+func (p *Plugin) ensureGitHubClient(config *configuration) error {
+	p.githubLock.Lock()
+	defer p.githubLock.Unlock()
+
+	if p.github != nil && p.githubCredentials == config.getGitHubCredentials() && p.githubBaseURL == config.GitHubBaseURL && p.githubProxyURL == config.GitHubProxyURL {
+		return nil
+	}
+
+	client, err := newGitHubClient(config)
+	if err != nil {
+		return err
+	}
+
+	p.github = client
+	p.githubCredentials = config.getGitHubCredentials()
+	p.githubBaseURL = config.GitHubBaseURL
+	p.githubProxyURL = config.GitHubProxyURL
 
 	return nil
 }
 
-// ServeHTTP demonstrates a plugin that handles HTTP requests by greeting the world.
+// getEnterpriseUploadURL derives the upload URL of a GitHub Enterprise instance from its API base
+// URL, e.g. https://github.example.com/api/v3/ becomes https://github.example.com/api/uploads/.
+func getEnterpriseUploadURL(baseURL string) string {
+	trimmed := strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(trimmed, "/api/v3") {
+		return strings.TrimSuffix(trimmed, "/api/v3") + "/api/uploads/"
+	}
+	return baseURL
+}
+
+// ServeHTTP routes requests to the plugin's endpoints under /plugins/<plugin-id>: creating issues
+// from posts and the dialog that does so, acting on created issues, the GitHub webhook, OAuth, and
+// the configuration, metrics, rate limit and health endpoints. Unknown paths are answered with 404.
 func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
 	switch r.URL.Path {
+	case "/config":
+		p.handleGetConfig(w, r)
 	case "/create":
+		if p.handleCORS(w, r) {
+			return
+		}
 		p.handleCreate(w, r)
+	case "/dialog":
+		p.handleOpenDialog(w, r)
+	case "/dialog/submit":
+		p.handleSubmitDialog(w, r)
+	case "/webhook":
+		p.handleWebhook(w, r)
+	case "/metrics":
+		p.handleMetrics(w, r)
+	case "/ratelimit":
+		p.handleRateLimit(w, r)
+	case "/health":
+		p.handleHealth(w, r)
+	case "/comment":
+		p.handleComment(w, r)
+	case "/reopen":
+		p.handleReopen(w, r)
+	case "/close":
+		p.handleClose(w, r)
+	case "/assign":
+		p.handleAssign(w, r)
+	case "/oauth/connect":
+		p.handleOAuthConnect(w, r)
+	case "/oauth/complete":
+		p.handleOAuthComplete(w, r)
 	default:
 		http.NotFound(w, r)
 	}
 }
 
+// issueTitlePrefixes maps each issue type to the default prefix prepended to the titles of its
+// issues. The prefix of documentation types can be overridden with the TitlePrefix setting.
+var issueTitlePrefixes = map[string]string{
+	"admin":     defaultTitlePrefix,
+	"developer": defaultTitlePrefix,
+	"handbook":  defaultTitlePrefix,
+	"feature":   "Feature Request: ",
+}
+
+// issueTypeDisplayNames maps each issue type to its name as shown to users.
+var issueTypeDisplayNames = map[string]string{
+	"admin":     "Admin",
+	"developer": "Developer",
+	"handbook":  "Company Handbook",
+	"feature":   "Feature Request",
+}
+
+// ConfigAPIResponse describes the documentation types that have a repository configured, so that
+// the webapp only offers valid options.
+type ConfigAPIResponse struct {
+	Types []*ConfigAPIType `json:"types"`
+}
+
+// ConfigAPIType describes an enabled documentation type. The first of its repositories is the
+// default.
+type ConfigAPIType struct {
+	Type         string   `json:"type"`
+	DisplayName  string   `json:"display_name"`
+	Repositories []string `json:"repositories"`
+}
+
+func (p *Plugin) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	config := p.getConfiguration()
+
+	configResponse := &ConfigAPIResponse{Types: []*ConfigAPIType{}}
+	for _, issueType := range config.getEnabledTypes() {
+		configResponse.Types = append(configResponse.Types, &ConfigAPIType{
+			Type:         issueType,
+			DisplayName:  issueTypeDisplayNames[issueType],
+			Repositories: config.getRepositories(issueType),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(configResponse); err != nil {
+		p.API.LogError("Unable to encode JSON", "user_id", userID, "error", err.Error())
+	}
+}
+
 type CreateAPIRequest struct {
-	Type   string `json:"type"`
-	Title  string `json:"title"`
-	Body   string `json:"body"`
-	PostID string `json:"post_id"`
+	Type   string   `json:"type"`
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	PostID string   `json:"post_id"`
+	Labels []string `json:"labels"`
+
+	// PostIDs optionally files several posts as a single issue, listing the message of each with
+	// a link to it in place of Body. PostID defaults to the first of them and is replied to as the
+	// marked post.
+	PostIDs []string `json:"post_ids"`
+
+	// Repository optionally selects one of the repositories configured for Type. The first
+	// configured repository is used when it is empty.
+	Repository string `json:"repository"`
+
+	// DryRun renders the issue and returns it in the response without creating it or replying to
+	// the post.
+	DryRun bool `json:"dry_run"`
+}
+
+type CreateAPIResponse struct {
+	IssueURL    string `json:"issue_url"`
+	IssueNumber int    `json:"issue_number"`
+	Existing    bool   `json:"existing"`
+
+	// Title is the title the issue was filed with, after prefixing it and truncating it to
+	// MaxTitleLength characters.
+	Title string `json:"title"`
+
+	// BodyLength is the number of characters of the post body included in the issue, which is
+	// less than the length of the post body when Truncated is set.
+	BodyLength int  `json:"body_length"`
+	Truncated  bool `json:"truncated"`
+
+	// ConfirmationFailed is set if the issue was filed but the post confirming it could not be
+	// created.
+	ConfirmationFailed bool `json:"confirmation_failed,omitempty"`
+
+	// Preview is the issue that would have been created, set only for dry runs.
+	Preview *IssuePreview `json:"preview,omitempty"`
+}
+
+// IssuePreview describes the issue a dry run would have created.
+type IssuePreview struct {
+	Repository string   `json:"repository"`
+	Title      string   `json:"title"`
+	Body       string   `json:"body"`
+	Labels     []string `json:"labels"`
+	Assignees  []string `json:"assignees"`
+	Milestone  int      `json:"milestone,omitempty"`
+}
+
+// logFields returns the key value pairs identifying the request in structured logs.
+func (r *CreateAPIRequest) logFields(userID string) []interface{} {
+	return []interface{}{"user_id", userID, "type", r.Type, "post_id", r.PostID}
+}
+
+// validate checks that the fields required to create an issue are set, returning an error listing
+// any that are missing.
+func (r *CreateAPIRequest) validate() error {
+	missing := []string{}
+	if strings.TrimSpace(r.Type) == "" {
+		missing = append(missing, "type")
+	}
+	if strings.TrimSpace(r.Title) == "" {
+		missing = append(missing, "title")
+	}
+	if strings.TrimSpace(r.PostID) == "" {
+		missing = append(missing, "post_id")
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	if postIDs := r.batchPostIDs(); len(postIDs) > maxBatchPosts {
+		return errors.Errorf("at most %d posts can be filed as one issue", maxBatchPosts)
+	}
+	return nil
+}
+
+// maxBatchPosts bounds the number of posts filed as a single issue, keeping the issue body within
+// GitHub's limits with the default MaxBodyLength, which applies to each post.
+const maxBatchPosts = 5
+
+// batchPostIDs returns the IDs of the posts to file as one issue, starting with PostID and
+// without duplicates, or nil if the request is not for several posts.
+func (r *CreateAPIRequest) batchPostIDs() []string {
+	if len(r.PostIDs) == 0 {
+		return nil
+	}
+
+	postIDs := []string{r.PostID}
+	for _, postID := range r.PostIDs {
+		duplicate := postID == ""
+		for _, seen := range postIDs {
+			duplicate = duplicate || postID == seen
+		}
+		if !duplicate {
+			postIDs = append(postIDs, postID)
+		}
+	}
+	return postIDs
+}
+
+// withLogFields returns fields extended with keyValuePairs. The given fields are not modified, so
+// they can be shared between log calls.
+func withLogFields(fields []interface{}, keyValuePairs ...interface{}) []interface{} {
+	return append(append([]interface{}{}, fields...), keyValuePairs...)
+}
+
+// requireMethod checks that r uses the given method, writing a 405 status listing it as allowed
+// otherwise.
+func requireMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeJSONError(w, http.StatusMethodNotAllowed, "Method "+r.Method+" is not allowed, use "+method)
+	return false
+}
+
+// requireJSONContentType reports whether the request declares a JSON body, responding with a 415
+// status otherwise.
+func requireJSONContentType(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && mediaType == "application/json" {
+		return true
+	}
+
+	writeJSONError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+	return false
+}
+
+// writeJSON responds with the given status and v encoded as JSON.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError responds with the given status and an ErrorAPIResponse holding message. Encoding
+// only fails once the client has gone away, so the error is dropped.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	_ = writeJSON(w, status, &ErrorAPIResponse{Error: message})
+}
+
+// ErrorAPIResponse is returned with every error status of the create endpoint, other than 429, and
+// of the endpoints acting on created issues, so that the webapp can report the failure to the user.
+type ErrorAPIResponse struct {
+	Error string `json:"error"`
+
+	// IssueURL links to the issue a post was recently marked with, set only with a 409 status.
+	IssueURL string `json:"issue_url,omitempty"`
+}
+
+// RateLimitAPIResponse is returned with a 429 status when GitHub's rate limit has been reached.
+type RateLimitAPIResponse struct {
+	Error   string    `json:"error"`
+	ResetAt time.Time `json:"reset_at"`
+
+	// RetryAfter is the number of seconds GitHub asked to wait, set only when its secondary rate
+	// limit was reached. It is also sent as the Retry-After header.
+	RetryAfter int `json:"retry_after,omitempty"`
 }
 
 func (p *Plugin) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
 	userID := r.Header.Get("Mattermost-User-ID")
 	if userID == "" {
-		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		writeJSONError(w, http.StatusUnauthorized, "Not authorized, please log in to Mattermost")
+		return
+	}
+
+	if !requireJSONContentType(w, r) {
 		return
 	}
 
 	var createRequest *CreateAPIRequest
-	decoder := json.NewDecoder(r.Body)
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, p.getConfiguration().getMaxRequestSize()))
+	decoder.DisallowUnknownFields()
 	err := decoder.Decode(&createRequest)
-	if err != nil {
-		p.API.LogError("Unable to decode JSON err=" + err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
+	if err != nil && err.Error() == requestTooLargeError {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Request body is too large, the limit is %d bytes", p.getConfiguration().getMaxRequestSize()))
+		return
+	}
+	if err != nil || createRequest == nil {
+		message := "Request body must be a JSON object"
+		if err != nil {
+			message = "Invalid request body: " + err.Error()
+		}
+		writeJSONError(w, http.StatusBadRequest, message)
+		return
+	}
+	if createRequest.Type == "" {
+		createRequest.Type = p.getConfiguration().DefaultType
+	}
+	if createRequest.PostID == "" && len(createRequest.PostIDs) > 0 {
+		createRequest.PostID = createRequest.PostIDs[0]
+	}
+	if err := createRequest.validate(); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	config := p.getConfiguration()
+	logFields := createRequest.logFields(userID)
 
-	ownerAndRepo := ""
-	switch createRequest.Type {
-	case "admin":
-		ownerAndRepo = config.AdminRepository
-	case "developer":
-		ownerAndRepo = config.DeveloperRepository
-	case "handbook":
-		ownerAndRepo = config.HandbookRepository
+	idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength))
+		return
 	}
+	saved := false
+	if idempotencyKey != "" {
+		result, reserved, err := p.reserveIdempotencyKey(userID, idempotencyKey)
+		if err != nil {
+			p.API.LogWarn("Unable to reserve idempotency key", withLogFields(logFields, "error", err.Error())...)
+		}
+		if result != nil && result.InProgress {
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("A request with this %s is already in progress", idempotencyKeyHeader))
+			return
+		}
+		if result != nil {
+			w.Header().Set(idempotentReplayedHeader, "true")
+			if err := writeJSON(w, result.Status, result.Response); err != nil {
+				p.API.LogError("Unable to encode JSON", withLogFields(logFields, "error", err.Error())...)
+			}
+			return
+		}
+		if reserved {
+			defer func() {
+				if saved {
+					return
+				}
+				if err := p.releaseIdempotencyKey(userID, idempotencyKey); err != nil {
+					p.API.LogWarn("Unable to release idempotency key", withLogFields(logFields, "error", err.Error())...)
+				}
+			}()
+		}
+	}
+
+	ctx, cancel := p.requestContext(r.Context())
+	defer cancel()
 
-	if ownerAndRepo == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	createResponse, err := p.createIssueFromPost(ctx, userID, createRequest)
+	p.metrics.recordCreate(createResponse, err)
+	if issueErr, ok := err.(*issueError); ok && issueErr.status == http.StatusTooManyRequests {
+		retryAfter := int(math.Ceil(issueErr.retryAfter.Seconds()))
+		if retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		}
+		if err := writeJSON(w, http.StatusTooManyRequests, &RateLimitAPIResponse{
+			Error:      issueErr.message,
+			ResetAt:    issueErr.resetAt,
+			RetryAfter: retryAfter,
+		}); err != nil {
+			p.API.LogError("Unable to encode JSON", withLogFields(logFields, "error", err.Error())...)
+		}
 		return
 	}
-
-	repoSplit := strings.Split(ownerAndRepo, "/")
-	if len(repoSplit) != 2 {
-		p.API.LogError("Bad configured repo: " + ownerAndRepo)
-		w.WriteHeader(http.StatusInternalServerError)
+	if issueErr, ok := err.(*issueError); ok {
+		if err := writeJSON(w, issueErr.status, &ErrorAPIResponse{Error: issueErr.message, IssueURL: issueErr.issueURL}); err != nil {
+			p.API.LogError("Unable to encode JSON", withLogFields(logFields, "error", err.Error())...)
+		}
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Unable to create the documentation issue, please try again later")
 		return
 	}
 
-	owner := repoSplit[0]
-	repo := repoSplit[1]
+	status := http.StatusCreated
+	if createResponse.Existing || createResponse.Preview != nil {
+		status = http.StatusOK
+	}
+
+	// Previews are not replayed, so that a dry run can be followed by the actual request.
+	if idempotencyKey != "" && createResponse.Preview == nil {
+		if err := p.saveIdempotentResult(userID, idempotencyKey, &idempotentResult{Status: status, Response: createResponse}); err != nil {
+			p.API.LogWarn("Unable to save idempotent result", withLogFields(logFields, "error", err.Error())...)
+		} else {
+			saved = true
+		}
+	}
+
+	if err := writeJSON(w, status, createResponse); err != nil {
+		p.API.LogError("Unable to encode JSON", withLogFields(logFields, "error", err.Error())...)
+	}
+}
+
+// issueError is returned by createIssueFromPost and carries the HTTP status that best describes
+// the failure, so that callers other than the HTTP handler can still report a readable message.
+type issueError struct {
+	status  int
+	message string
+
+	// resetAt is when GitHub's rate limit resets, set only for rate limit failures. retryAfter is
+	// how long GitHub asked to wait, set only for secondary rate limit failures.
+	resetAt    time.Time
+	retryAfter time.Duration
+
+	// issueURL is the issue the post was recently marked with, set only for conflicts.
+	issueURL string
+}
+
+func (e *issueError) Error() string {
+	return e.message
+}
+
+// getSiteURL returns the Site URL of the Mattermost server, or an error if it is not configured.
+func (p *Plugin) getSiteURL() (string, error) {
+	siteURL := p.API.GetConfig().ServiceSettings.SiteURL
+	if siteURL == nil || *siteURL == "" {
+		return "", errors.New("SiteURL is not configured")
+	}
+	return *siteURL, nil
+}
+
+// postNotFoundMessage is the issueError message used when the marked post does not exist.
+const postNotFoundMessage = "post not found or deleted"
+
+// requestTooLargeError is the error returned when reading beyond the limit of an
+// http.MaxBytesReader.
+const requestTooLargeError = "http: request body too large"
+
+func newIssueError(status int, message string) error {
+	return &issueError{status: status, message: message}
+}
+
+// createIssueFromPost files a GitHub issue for the post referenced by createRequest on behalf of
+// the given user, and replies in the post's thread with a link to the issue. When deduplication
+// is enabled, an existing open issue with the same title is commented on instead. Every GitHub
+// call is cancelled along with requestCtx, and no issue is filed once it is done.
+func (p *Plugin) createIssueFromPost(requestCtx context.Context, userID string, createRequest *CreateAPIRequest) (*CreateAPIResponse, error) {
+	config := p.getConfiguration()
+	logFields := createRequest.logFields(userID)
+
+	repositories := config.getRepositories(createRequest.Type)
+	if len(repositories) == 0 {
+		return nil, newIssueError(http.StatusBadRequest, "Unknown documentation type: "+createRequest.Type)
+	}
+
+	ownerAndRepo := repositories[0]
+	if createRequest.Repository != "" {
+		ownerAndRepo = ""
+		for _, repository := range repositories {
+			if strings.EqualFold(repository, createRequest.Repository) {
+				ownerAndRepo = repository
+				break
+			}
+		}
+		if ownerAndRepo == "" {
+			ownerAndRepo = config.resolveOrganizationRepository(createRequest.Type, createRequest.Repository)
+		}
+		if ownerAndRepo == "" {
+			return nil, newIssueError(http.StatusBadRequest, "Repository "+createRequest.Repository+" is not configured for documentation type "+createRequest.Type)
+		}
+	}
+
+	labels := mergeLabels(config.getLabels(createRequest.Type), createRequest.Labels, parseLabels(config.getIdentifierLabel()))
 
-	labels := []string{}
-	if config.Labels != "" {
-		labels = strings.Split(config.Labels, ",")
+	assignees := []string{}
+	if config.Assignees != "" {
+		for _, assignee := range strings.Split(config.Assignees, ",") {
+			assignees = append(assignees, strings.TrimSpace(assignee))
+		}
 	}
 
 	user, appErr := p.API.GetUser(userID)
 	if appErr != nil {
-		p.API.LogError("Unable to get user err=" + appErr.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		p.API.LogError("Unable to get user", withLogFields(logFields, "error", appErr.Error())...)
+		return nil, newIssueError(http.StatusInternalServerError, "Unable to get user")
+	}
+	if !config.isUserAllowed(user) {
+		return nil, newIssueError(http.StatusForbidden, p.localize(user.Locale, msgNotPermitted))
+	}
+	if config.isBlocked(createRequest.Title, createRequest.Body) {
+		return nil, newIssueError(http.StatusUnprocessableEntity, p.localize(user.Locale, msgContentBlocked))
 	}
-
-	serverConfig := p.API.GetConfig()
 
 	docPost, appErr := p.API.GetPost(createRequest.PostID)
+	if appErr != nil && appErr.StatusCode == http.StatusNotFound {
+		return nil, newIssueError(http.StatusNotFound, p.localize(user.Locale, msgPostNotFound))
+	}
 	if appErr != nil {
-		p.API.LogError("Unable to get post err=" + appErr.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		p.API.LogError("Unable to get post", withLogFields(logFields, "error", appErr.Error())...)
+		return nil, newIssueError(http.StatusInternalServerError, "Unable to get post")
+	}
+	if docPost.DeleteAt != 0 {
+		return nil, newIssueError(http.StatusNotFound, p.localize(user.Locale, msgPostNotFound))
+	}
+
+	if !p.API.HasPermissionToChannel(userID, docPost.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		return nil, newIssueError(http.StatusForbidden, p.localize(user.Locale, msgCannotReadPost))
 	}
 
+	batchPosts := []*model.Post{}
+	for _, postID := range createRequest.batchPostIDs() {
+		batchPost := docPost
+		if postID != docPost.Id {
+			batchPost, appErr = p.API.GetPost(postID)
+			if (appErr != nil && appErr.StatusCode == http.StatusNotFound) || (appErr == nil && batchPost.DeleteAt != 0) {
+				return nil, newIssueError(http.StatusNotFound, p.localize(user.Locale, msgPostNotFound))
+			}
+			if appErr != nil {
+				p.API.LogError("Unable to get post", withLogFields(logFields, "batch_post_id", postID, "error", appErr.Error())...)
+				return nil, newIssueError(http.StatusInternalServerError, "Unable to get post")
+			}
+			if !p.API.HasPermissionToChannel(userID, batchPost.ChannelId, model.PERMISSION_READ_CHANNEL) {
+				return nil, newIssueError(http.StatusForbidden, p.localize(user.Locale, msgCannotReadPost))
+			}
+		}
+		if config.isBlocked(batchPost.Message) {
+			return nil, newIssueError(http.StatusUnprocessableEntity, p.localize(user.Locale, msgContentBlocked))
+		}
+		batchPosts = append(batchPosts, batchPost)
+	}
+
+	// submitted is set once the issue is filed, after which the reservation of the post for the
+	// submit cooldown is kept rather than released.
+	submitted := false
+	if !createRequest.DryRun {
+		recent, reserved, err := p.reserveSubmission(docPost.Id)
+		if err != nil {
+			p.API.LogWarn("Unable to check for a recent submission", withLogFields(logFields, "error", err.Error())...)
+		}
+		if recent != nil {
+			return nil, &issueError{
+				status:   http.StatusConflict,
+				message:  p.localize(user.Locale, msgRecentlySubmitted),
+				issueURL: recent.IssueURL,
+			}
+		}
+		if reserved {
+			defer func() {
+				if submitted {
+					return
+				}
+				if err := p.releaseSubmission(docPost.Id); err != nil {
+					p.API.LogWarn("Unable to release submission", withLogFields(logFields, "error", err.Error())...)
+				}
+			}()
+		}
+	}
+
+	if assignee := config.getOnCallAssignee(docPost.ChannelId, time.Now()); assignee != "" {
+		assignees = []string{assignee}
+	}
+	if authorAssignee := p.getAuthorGitHubUsername(requestCtx, config, docPost.UserId, logFields); authorAssignee != "" {
+		assigned := false
+		for _, assignee := range assignees {
+			assigned = assigned || strings.EqualFold(assignee, authorAssignee)
+		}
+		if !assigned {
+			assignees = append(assignees, authorAssignee)
+		}
+	}
+
+	if createRequest.Repository == "" {
+		if channelRepository := config.getChannelRepository(docPost.ChannelId); channelRepository != "" {
+			ownerAndRepo = channelRepository
+		} else if teamRepository := p.resolveTeamRepository(config, docPost.ChannelId, createRequest.Type, logFields); teamRepository != "" {
+			ownerAndRepo = teamRepository
+		}
+	}
+
+	logFields = withLogFields(logFields, "repo", ownerAndRepo)
+
+	owner, repo, err := splitOwnerAndRepo(ownerAndRepo)
+	if err != nil {
+		p.API.LogError("Bad configured repo", logFields...)
+		return nil, newIssueError(http.StatusInternalServerError, p.localize(user.Locale, msgMisconfiguredRepo))
+	}
+
+	// The confirmation is posted in the marked post's thread as a direct reply to the marked post,
+	// whether that post is the thread root or a reply within it.
 	rootID := docPost.RootId
 	if rootID == "" {
 		rootID = docPost.Id
 	}
+	parentID := docPost.Id
 
-	permalink, err := url.Parse(*serverConfig.ServiceSettings.SiteURL)
-	permalink.Path = path.Join(permalink.Path, "_redirect", "pl", docPost.Id)
+	siteURL, err := p.getSiteURL()
+	if err != nil {
+		p.API.LogError("Unable to get site URL", withLogFields(logFields, "error", err.Error())...)
+		return nil, newIssueError(http.StatusInternalServerError, "The Site URL of the Mattermost server is not configured")
+	}
 
-	body := fmt.Sprintf("Mattermost user `%s` from %s has requested the following be documented:\n\n```\n%s\n```\n\nSee the original post [here](%s).\n\n_This issue was generated from [Mattermost](https://mattermost.com) using the [Doc Up](https://github.com/jwilander/mattermost-plugin-docup) plugin._",
-		user.Username,
-		*serverConfig.ServiceSettings.SiteURL,
-		createRequest.Body,
-		permalink.String(),
-	)
+	siteBaseURL, err := url.Parse(siteURL)
+	if err != nil {
+		p.API.LogError("Unable to parse site URL", withLogFields(logFields, "error", err.Error())...)
+		return nil, newIssueError(http.StatusInternalServerError, "The Site URL of the Mattermost server is invalid")
+	}
+	postPermalink := func(postID string) *url.URL {
+		permalink := *siteBaseURL
+		permalink.Path = path.Join(siteBaseURL.Path, "_redirect", "pl", postID)
+		return &permalink
+	}
+	permalink := postPermalink(docPost.Id)
+
+	attachments := p.getAttachments(siteURL, docPost.FileIds, logFields)
+
+	channelName, teamName := p.getChannelAndTeamNames(docPost.ChannelId, logFields)
+
+	postBody, bodyLength, truncated := config.preparePostBody(createRequest.Body)
+
+	// The messages of posts filed together replace the body of the request.
+	var posts []issuePost
+	if len(batchPosts) > 0 {
+		bodyLength, truncated = 0, false
+	}
+	for _, batchPost := range batchPosts {
+		batchBody, batchLength, batchTruncated := config.preparePostBody(batchPost.Message)
+		posts = append(posts, issuePost{Permalink: postPermalink(batchPost.Id).String(), Body: batchBody})
+		bodyLength += batchLength
+		truncated = truncated || batchTruncated
+	}
+
+	var threadPosts []issueThreadPost
+	var omittedThreadPosts int
+	if config.IncludeThread {
+		threadPosts, omittedThreadPosts = p.getThreadPosts(config, rootID, append([]*model.Post{docPost}, batchPosts...), logFields)
+	}
+
+	body, err := config.renderIssueBody(&issueBodyData{
+		Type:        createRequest.Type,
+		Username:    user.Username,
+		SiteURL:     siteURL,
+		Body:        postBody,
+		Permalink:   permalink.String(),
+		ChannelName: channelName,
+		TeamName:    teamName,
+		Attachments: attachments,
+		Posts:       posts,
+
+		ThreadPosts:        threadPosts,
+		OmittedThreadPosts: omittedThreadPosts,
+	})
+	if err != nil {
+		p.API.LogError("Unable to render issue body", withLogFields(logFields, "error", err.Error())...)
+		return nil, newIssueError(http.StatusInternalServerError, "Unable to render issue body")
+	}
+
+	title, err := config.renderIssueTitle(&issueTitleData{
+		Title:       createRequest.Title,
+		Type:        createRequest.Type,
+		Username:    user.Username,
+		ChannelName: channelName,
+		TeamName:    teamName,
+	})
+	if err != nil {
+		p.API.LogError("Unable to render issue title", withLogFields(logFields, "error", err.Error())...)
+		return nil, newIssueError(http.StatusInternalServerError, "Unable to render issue title")
+	}
+	title, _ = truncateTitle(title, config.getMaxTitleLength())
 
 	issueRequest := &github.IssueRequest{
-		Title:  NewString("Request for Documentation: " + createRequest.Title),
-		Body:   NewString(body),
-		Labels: &labels,
+		Title: NewString(title),
+		Body:  NewString(body),
+	}
+	if len(labels) > 0 {
+		issueRequest.Labels = &labels
+	}
+	if len(assignees) > 0 {
+		issueRequest.Assignees = &assignees
+	}
+
+	client := p.getGitHubClientForUser(userID)
+
+	if templatePath := strings.TrimSpace(config.TemplatePath); templatePath != "" && config.isGitHub() {
+		ctx, cancel := p.githubRequestContext(requestCtx)
+		template, err := p.getIssueTemplate(ctx, client, owner, repo, templatePath)
+		cancel()
+		if err != nil {
+			p.API.LogWarn("Unable to get issue template, using the default body", withLogFields(logFields, "template_path", templatePath, "error", err.Error())...)
+		} else {
+			issueRequest.Body = NewString(applyIssueTemplate(template, body))
+		}
+	}
+
+	if milestone := config.getMilestone(createRequest.Type); milestone != "" && config.isGitHub() {
+		ctx, cancel := p.githubRequestContext(requestCtx)
+		number, err := resolveMilestone(ctx, client, owner, repo, milestone)
+		cancel()
+		if err != nil {
+			p.API.LogWarn("Unable to resolve milestone, creating the issue without one", withLogFields(logFields, "milestone", milestone, "error", err.Error())...)
+		} else {
+			issueRequest.Milestone = &number
+		}
 	}
 
-	issue, _, err := p.github.Issues.Create(context.Background(), owner, repo, issueRequest)
+	if createRequest.DryRun {
+		return &CreateAPIResponse{
+			Title:      title,
+			BodyLength: bodyLength,
+			Truncated:  truncated,
+			Preview: &IssuePreview{
+				Repository: owner + "/" + repo,
+				Title:      issueRequest.GetTitle(),
+				Body:       issueRequest.GetBody(),
+				Labels:     labels,
+				Assignees:  assignees,
+				Milestone:  issueRequest.GetMilestone(),
+			},
+		}, nil
+	}
+
+	// Filing the issue cannot be undone, so give up if the client has gone away or the request
+	// has timed out in the meantime.
+	if err := requestCtx.Err(); err != nil {
+		return nil, p.convertGitHubError(err, time.Now(), userID, docPost.ChannelId, rootID, "Error creating issue", logFields)
+	}
+
+	var issue *github.Issue
+	if config.DeduplicatePosts && config.isGitHub() {
+		marked, err := p.getPostIssue(docPost.Id)
+		if err != nil {
+			p.API.LogWarn("Unable to check for an issue already created for the post", withLogFields(logFields, "error", err.Error())...)
+		}
+		// Marking the post for another repository still creates an issue there.
+		if marked != nil && strings.EqualFold(marked.Repository, owner+"/"+repo) {
+			issue = &github.Issue{
+				Number:  &marked.IssueNumber,
+				HTMLURL: &marked.IssueURL,
+			}
+		}
+	}
+	if issue == nil && config.DeduplicateIssues && config.isGitHub() {
+		ctx, cancel := p.githubRequestContext(requestCtx)
+		issue, err = findDuplicateIssue(ctx, client, owner, repo, issueRequest.GetTitle(), config.getIdentifierLabel(), config.getIssueMarker())
+		cancel()
+		if err != nil {
+			p.API.LogWarn("Unable to search for duplicate GitHub issues", withLogFields(logFields, "error", err.Error())...)
+		}
+	}
+
+	existing := issue != nil
+	if existing {
+		comment := &github.IssueComment{
+			Body: NewString(fmt.Sprintf("Mattermost user `%s` has requested this be documented again.", user.Username)),
+		}
+		if config.includePermalink() {
+			comment.Body = NewString(fmt.Sprintf("Mattermost user `%s` from %s has requested this be documented again. See the post [here](%s).",
+				user.Username,
+				siteURL,
+				permalink.String(),
+			))
+		}
+		ctx, cancel := p.githubRequestContext(requestCtx)
+		started := time.Now()
+		_, _, err = client.Issues.CreateComment(ctx, owner, repo, issue.GetNumber(), comment)
+		cancel()
+		if err != nil {
+			p.API.LogError("Error commenting on GitHub issue", withLogFields(logFields, "error", err.Error())...)
+			return nil, p.convertGitHubError(err, started, userID, docPost.ChannelId, rootID, "Error commenting on existing GitHub issue", logFields)
+		}
+	} else {
+		ctx, cancel := p.githubRequestContext(requestCtx)
+		started := time.Now()
+		if config.getMode() == modePRStub {
+			issue, err = p.createDocStub(ctx, client, owner, repo, docPost.Id, issueRequest)
+			if err != nil {
+				p.API.LogWarn("Unable to open documentation stub pull request, creating an issue instead", withLogFields(logFields, "error", err.Error())...)
+				issue = nil
+			}
+		}
+		stub := issue != nil
+		if stub {
+			p.labelDocStub(ctx, client, owner, repo, issue.GetNumber(), issueRequest, logFields)
+		} else {
+			issue, err = p.createIssue(ctx, client, owner, repo, issueRequest)
+		}
+		if err != nil && issueRequest.Assignees != nil && isValidationError(err) {
+			// GitHub rejects the whole request when an assignee is not a collaborator, so retry
+			// without assignees rather than losing the documentation request.
+			p.API.LogError("Unable to assign GitHub issue, creating it unassigned", withLogFields(logFields, "error", err.Error())...)
+			issueRequest.Assignees = nil
+			issue, err = p.createIssue(ctx, client, owner, repo, issueRequest)
+		}
+		cancel()
+		if err != nil {
+			p.API.LogError("Error creating issue", withLogFields(logFields, "error", err.Error())...)
+			return nil, p.convertGitHubError(err, started, userID, docPost.ChannelId, rootID, "Error creating issue", logFields)
+		}
+
+		if err := p.saveIssueMapping(owner+"/"+repo, issue.GetNumber(), &issueMapping{
+			ChannelID: docPost.ChannelId,
+			RootID:    rootID,
+			PostID:    docPost.Id,
+		}); err != nil {
+			p.API.LogWarn("Unable to save issue mapping", withLogFields(logFields, "error", err.Error())...)
+		}
+
+		if config.isGitHub() {
+			if err := p.addUserHistory(userID, newUserHistoryEntry(owner+"/"+repo, issue.GetNumber(), issue.GetHTMLURL(), issueRequest.GetTitle())); err != nil {
+				p.API.LogWarn("Unable to save user history", withLogFields(logFields, "error", err.Error())...)
+			}
+		}
+
+		if githubIssueType := strings.TrimSpace(config.IssueType); githubIssueType != "" && !stub && config.isGitHub() {
+			ctx, cancel := p.githubRequestContext(requestCtx)
+			p.setGitHubIssueType(ctx, client, owner, repo, issue.GetNumber(), githubIssueType, logFields)
+			cancel()
+		}
+
+		if config.LockIssues && config.isGitHub() {
+			var opt *github.LockIssueOptions
+			if config.LockReason != "" {
+				opt = &github.LockIssueOptions{LockReason: config.LockReason}
+			}
+			ctx, cancel := p.githubRequestContext(requestCtx)
+			_, err := client.Issues.Lock(ctx, owner, repo, issue.GetNumber(), opt)
+			cancel()
+			if err != nil {
+				p.API.LogWarn("Unable to lock GitHub issue", withLogFields(logFields, "error", err.Error())...)
+			}
+		}
+
+		if config.DeduplicatePosts && config.isGitHub() {
+			if err := p.savePostIssue(docPost.Id, &postIssue{
+				Repository:  owner + "/" + repo,
+				IssueNumber: issue.GetNumber(),
+				IssueURL:    issue.GetHTMLURL(),
+			}); err != nil {
+				p.API.LogWarn("Unable to save post issue", withLogFields(logFields, "error", err.Error())...)
+			}
+		}
+
+		if config.SyncThreadReplies && config.isGitHub() {
+			if err := p.saveThreadIssue(rootID, &threadIssue{
+				Repository:  owner + "/" + repo,
+				IssueNumber: issue.GetNumber(),
+			}); err != nil {
+				p.API.LogWarn("Unable to save thread issue", withLogFields(logFields, "error", err.Error())...)
+			}
+		}
+
+		if columnID, ok := config.getProjectColumnID(); ok && config.isGitHub() {
+			ctx, cancel := p.githubRequestContext(requestCtx)
+			contentType := "Issue"
+			if stub {
+				contentType = "PullRequest"
+			}
+			_, _, err := client.Projects.CreateProjectCard(ctx, columnID, &github.ProjectCardOptions{
+				ContentID:   issue.GetID(),
+				ContentType: contentType,
+			})
+			cancel()
+			if err != nil {
+				p.API.LogWarn("Unable to add issue to project", withLogFields(logFields, "error", err.Error())...)
+			}
+		}
+	}
+
+	message, ok, err := config.renderConfirmation(&confirmationData{
+		Permalink:   permalink.String(),
+		IssueURL:    issue.GetHTMLURL(),
+		IssueNumber: issue.GetNumber(),
+		Existing:    existing,
+		Footer:      config.getPostFooter(),
+	})
 	if err != nil {
-		p.API.LogError("Error creating GitHub issue err=" + err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		p.API.LogWarn("Unable to render confirmation template", withLogFields(logFields, "error", err.Error())...)
 	}
+	if !ok {
+		message = p.localize(user.Locale, msgConfirmationCreated, permalink.String(), issue.GetHTMLURL())
+		if existing {
+			message = p.localize(user.Locale, msgConfirmationExisting, permalink.String(), issue.GetHTMLURL())
+		}
 
+		if footer := config.getPostFooter(); footer != "" {
+			message += "\n\n" + footer
+		}
+	}
+
+	postUserID := p.botUserID
+	if postUserID == "" {
+		postUserID = userID
+	}
 
 	post := &model.Post{
-		UserId:    userID,
+		UserId:    postUserID,
 		ChannelId: docPost.ChannelId,
 		RootId:    rootID,
-		Message:   fmt.Sprintf("Marked [this post](%s) for documentation [here](%s).\n\n_Generated by the [Doc Up](https://github.com/jwilander/mattermost-plugin-docup) plugin._", permalink.String(), issue.GetHTMLURL()),
+		ParentId:  parentID,
+		Message:   message,
+	}
+	attachment := newConfirmationAttachment(issue, existing, message, p.localize(user.Locale, msgConfirmationOpenIssue))
+	if config.isAssignable(createRequest.Type) {
+		attachment.Actions = []*model.PostAction{newAssignAction(owner, repo, issue.GetNumber())}
 	}
+	post.AddProp("attachments", []*model.SlackAttachment{attachment})
+	post.AddProp(issueURLProp, issue.GetHTMLURL())
+	post.AddProp(issueNumberProp, issue.GetNumber())
 
-	_, appErr = p.API.CreatePost(post)
+	// The issue has been filed by now, so failing to confirm it is logged rather than returned. The
+	// caller is still given the issue, and retrying would only file it again.
+	confirmationFailed := false
+	switch config.getConfirmationVisibility() {
+	case confirmationEphemeral:
+		p.API.SendEphemeralPost(userID, post)
+	case confirmationNone:
+	default:
+		if channelID := config.ConfirmationChannelID; channelID != "" {
+			post.ChannelId = channelID
+			post.RootId = ""
+			post.ParentId = ""
+		}
+		if _, appErr = p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Unable to create confirmation post", withLogFields(logFields, "issue_url", issue.GetHTMLURL(), "error", appErr.Error())...)
+			confirmationFailed = true
+		}
+	}
+
+	if _, appErr = p.API.AddReaction(&model.Reaction{
+		UserId:    postUserID,
+		PostId:    docPost.Id,
+		EmojiName: config.getConfirmationEmoji(),
+	}); appErr != nil {
+		p.API.LogWarn("Unable to add reaction", withLogFields(logFields, "error", appErr.Error())...)
+	}
+
+	createResponse := &CreateAPIResponse{
+		IssueURL:    issue.GetHTMLURL(),
+		IssueNumber: issue.GetNumber(),
+		Title:       title,
+		Existing:    existing,
+		BodyLength:  bodyLength,
+		Truncated:   truncated,
+
+		ConfirmationFailed: confirmationFailed,
+	}
+
+	submitted = true
+	if err := p.saveSubmission(docPost.Id, createResponse); err != nil {
+		p.API.LogWarn("Unable to record submission", withLogFields(logFields, "error", err.Error())...)
+	}
+
+	return createResponse, nil
+}
+
+const (
+	// issueURLProp and issueNumberProp are the props of confirmation posts holding the issue the
+	// post was marked with, for the webapp to render without querying the issue tracker.
+	issueURLProp    = "docup_issue_url"
+	issueNumberProp = "docup_issue_number"
+)
+
+const (
+	// createdIssueColor and existingIssueColor are the colors of the bar of confirmation
+	// attachments for new and existing issues respectively.
+	createdIssueColor  = "#2ea44f"
+	existingIssueColor = "#0366d6"
+)
+
+// newConfirmationAttachment renders the confirmation of a post marked for documentation as a
+// message attachment linking to the issue, with message as the plain-text fallback and openIssue
+// as the text of the link to the issue. Interactive buttons can only call back to integrations,
+// so the issue is opened through the title and a link styled as an action.
+func newConfirmationAttachment(issue *github.Issue, existing bool, message, openIssue string) *model.SlackAttachment {
+	title := issue.GetTitle()
+	if title == "" {
+		title = fmt.Sprintf("#%d", issue.GetNumber())
+	}
+
+	color := createdIssueColor
+	if existing {
+		color = existingIssueColor
+	}
+
+	return &model.SlackAttachment{
+		Fallback:  message,
+		Color:     color,
+		Title:     title,
+		TitleLink: issue.GetHTMLURL(),
+		Text:      fmt.Sprintf("[%s](%s)", openIssue, issue.GetHTMLURL()),
+	}
+}
+
+// createIssue creates the issue with the configured provider, using the given client for GitHub,
+// and retrying transient failures up to the configured number of attempts.
+func (p *Plugin) createIssue(ctx context.Context, client *github.Client, owner, repo string, issueRequest *github.IssueRequest) (*github.Issue, error) {
+	creator := p.getIssueCreator(client)
+
+	var issue *github.Issue
+	err := withRetry(ctx, p.getConfiguration().getMaxRetries(), func() error {
+		var err error
+		issue, err = creator.CreateIssue(ctx, owner, repo, issueRequest)
+		return err
+	})
+	return issue, err
+}
+
+// githubContext returns a context bounding a GitHub call by the configured timeout. It is also
+// cancelled when the plugin is deactivated.
+func (p *Plugin) githubContext() (context.Context, context.CancelFunc) {
+	return p.githubRequestContext(p.pluginContext())
+}
+
+// githubRequestContext returns a context bounding a GitHub call made while serving the request
+// of the given context by the configured timeout. It is also cancelled along with the request.
+func (p *Plugin) githubRequestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, p.getConfiguration().getGitHubTimeout())
+}
+
+// requestContext returns a context for serving the request of the given context, bounded by the
+// configured CreateTimeout. It is cancelled when the client disconnects, when the plugin is
+// deactivated or when the returned function is called, which must be once the request is served.
+func (p *Plugin) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, p.getConfiguration().getCreateTimeout())
+
+	pluginCtx := p.pluginContext()
+	go func() {
+		select {
+		case <-pluginCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// convertGitHubError maps an error from a GitHub call started at the given time to an issueError,
+// letting the requesting user know about rate limits. Any failure logged is tagged with logFields.
+func (p *Plugin) convertGitHubError(err error, started time.Time, userID, channelID, rootID, message string, logFields []interface{}) error {
+	if rateLimitErr, ok := err.(*github.RateLimitError); ok {
+		return p.handleRateLimitError(rateLimitErr, userID, channelID, rootID)
+	}
+	if abuseErr, ok := err.(*github.AbuseRateLimitError); ok {
+		return p.handleAbuseRateLimitError(abuseErr, userID, channelID, rootID)
+	}
+	if err == context.DeadlineExceeded {
+		p.API.LogError("Timed out waiting for GitHub", withLogFields(logFields, "elapsed", time.Since(started).String())...)
+		return newIssueError(http.StatusGatewayTimeout, "Timed out waiting for GitHub")
+	}
+	if err == context.Canceled {
+		p.API.LogWarn("Request cancelled while waiting for GitHub", withLogFields(logFields, "elapsed", time.Since(started).String())...)
+		return newIssueError(http.StatusServiceUnavailable, "The request was cancelled")
+	}
+	return newIssueError(http.StatusInternalServerError, message)
+}
+
+// handleRateLimitError lets the requesting user know that GitHub's rate limit has been reached and
+// when to retry, and returns the corresponding issueError.
+func (p *Plugin) handleRateLimitError(rateLimitErr *github.RateLimitError, userID, channelID, rootID string) error {
+	resetAt := rateLimitErr.Rate.Reset.Time
+	message := fmt.Sprintf("GitHub's rate limit has been reached, so this post could not be marked for documentation. Please try again after %s.", resetAt.UTC().Format(time.RFC1123))
+
+	p.API.SendEphemeralPost(userID, &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelID,
+		RootId:    rootID,
+		Message:   message,
+	})
+
+	return &issueError{
+		status:  http.StatusTooManyRequests,
+		message: message,
+		resetAt: resetAt,
+	}
+}
+
+// handleAbuseRateLimitError lets the requesting user know that GitHub's secondary rate limit has
+// been reached and when to retry, and returns the corresponding issueError.
+func (p *Plugin) handleAbuseRateLimitError(abuseErr *github.AbuseRateLimitError, userID, channelID, rootID string) error {
+	retryAfter := abuseErr.GetRetryAfter()
+	if retryAfter <= 0 {
+		retryAfter = defaultAbuseRetryAfter
+	}
+	resetAt := time.Now().Add(retryAfter).Truncate(time.Second)
+	message := fmt.Sprintf("GitHub's secondary rate limit has been reached, so this post could not be marked for documentation. Please try again after %s.", resetAt.UTC().Format(time.RFC1123))
+
+	p.API.SendEphemeralPost(userID, &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelID,
+		RootId:    rootID,
+		Message:   message,
+	})
+
+	return &issueError{
+		status:     http.StatusTooManyRequests,
+		message:    message,
+		resetAt:    resetAt,
+		retryAfter: retryAfter,
+	}
+}
+
+// mergeLabels combines the given label lists, dropping case-insensitive duplicates while keeping
+// the casing of the first occurrence.
+func mergeLabels(lists ...[]string) []string {
+	merged := []string{}
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, label := range list {
+			key := strings.ToLower(label)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, label)
+		}
+	}
+	return merged
+}
+
+// isValidationError reports whether err is a GitHub 422 Unprocessable Entity response.
+func isValidationError(err error) bool {
+	errResponse, ok := err.(*github.ErrorResponse)
+	return ok && errResponse.Response != nil && errResponse.Response.StatusCode == http.StatusUnprocessableEntity
+}
+
+// getAttachments describes the given files attached to a post, linking to each file on the
+// Mattermost server rather than uploading it. Files whose info cannot be read are skipped, and
+// logged with logFields.
+func (p *Plugin) getAttachments(siteURL string, fileIDs []string, logFields []interface{}) []issueAttachment {
+	attachments := []issueAttachment{}
+	for _, fileID := range fileIDs {
+		fileInfo, appErr := p.API.GetFileInfo(fileID)
+		if appErr != nil {
+			p.API.LogWarn("Unable to get file info", withLogFields(logFields, "file_id", fileID, "error", appErr.Error())...)
+			continue
+		}
+
+		fileURL, err := url.Parse(siteURL)
+		if err != nil {
+			p.API.LogWarn("Unable to parse site URL", withLogFields(logFields, "error", err.Error())...)
+			return attachments
+		}
+		fileURL.Path = path.Join(fileURL.Path, "api", "v4", "files", fileInfo.Id)
+
+		attachments = append(attachments, issueAttachment{
+			Name: fileInfo.Name,
+			URL:  fileURL.String(),
+		})
+	}
+	return attachments
+}
+
+// resolveTeamRepository returns the owner/repo configured for issues of the given type in the team
+// of the given channel, or an empty string if there is none. Failures are logged with logFields.
+func (p *Plugin) resolveTeamRepository(config *configuration, channelID, issueType string, logFields []interface{}) string {
+	if strings.TrimSpace(config.TeamRepositoryMap) == "" {
+		return ""
+	}
+
+	channel, appErr := p.API.GetChannel(channelID)
 	if appErr != nil {
-		p.API.LogError("Unable to create post err=" + appErr.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		p.API.LogWarn("Unable to get channel", withLogFields(logFields, "channel_id", channelID, "error", appErr.Error())...)
+		return ""
+	}
+	if channel.TeamId == "" {
+		return ""
+	}
+
+	return config.getTeamRepository(channel.TeamId, issueType)
+}
+
+// getChannelAndTeamNames returns the display names of a channel and of its team. Channels
+// without a display name, such as direct messages, are described by their type instead, and the
+// team name is empty for channels that do not belong to a team. Failures are logged with logFields.
+func (p *Plugin) getChannelAndTeamNames(channelID string, logFields []interface{}) (string, string) {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		p.API.LogWarn("Unable to get channel", withLogFields(logFields, "channel_id", channelID, "error", appErr.Error())...)
+		return "Unknown Channel", ""
+	}
+
+	channelName := channel.DisplayName
+	if channelName == "" {
+		switch channel.Type {
+		case model.CHANNEL_DIRECT:
+			channelName = "Direct Message"
+		case model.CHANNEL_GROUP:
+			channelName = "Group Message"
+		case model.CHANNEL_PRIVATE:
+			channelName = "Private Channel"
+		default:
+			channelName = "Public Channel"
+		}
+	}
+
+	if channel.TeamId == "" {
+		return channelName, ""
+	}
+
+	team, appErr := p.API.GetTeam(channel.TeamId)
+	if appErr != nil {
+		p.API.LogWarn("Unable to get team", withLogFields(logFields, "team_id", channel.TeamId, "error", appErr.Error())...)
+		return channelName, ""
+	}
+
+	return channelName, team.DisplayName
+}
+
+// resolveMilestone returns the number of the given milestone, which is either a milestone number
+// or the title of an open milestone in the repository.
+func resolveMilestone(ctx context.Context, client *github.Client, owner, repo, milestone string) (int, error) {
+	if number, err := strconv.Atoi(milestone); err == nil {
+		return number, nil
 	}
+
+	opt := &github.MilestoneListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		milestones, resp, err := client.Issues.ListMilestones(ctx, owner, repo, opt)
+		if err != nil {
+			return 0, err
+		}
+		for _, m := range milestones {
+			if strings.EqualFold(m.GetTitle(), milestone) {
+				return m.GetNumber(), nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return 0, errors.Errorf("no open milestone titled %q in %s/%s", milestone, owner, repo)
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// findDuplicateIssue returns the first open issue created by the plugin, as told by the given
+// marker, in the repository with exactly the given title, or nil if there is none.
+func findDuplicateIssue(ctx context.Context, client *github.Client, owner, repo, title, label, marker string) (*github.Issue, error) {
+	query := fmt.Sprintf("repo:%s/%s is:issue is:open in:title %q", owner, repo, title)
+	if label != "" {
+		query += fmt.Sprintf(" label:%q", label)
+	}
+	result, _, err := client.Search.Issues(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result.Issues {
+		if strings.EqualFold(result.Issues[i].GetTitle(), title) && strings.Contains(result.Issues[i].GetBody(), marker) {
+			return &result.Issues[i], nil
+		}
+	}
+
+	return nil, nil
 }
 
 func NewString(s string) *string { return &s }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	// modeIssue files an issue for each post marked for documentation, and modePRStub opens a pull
+	// request adding a placeholder document instead.
+	modeIssue  = "issue"
+	modePRStub = "pr-stub"
+
+	// defaultDocsDirectory is the directory of the repository placeholder documents are added to
+	// when DocsDirectory is not configured.
+	defaultDocsDirectory = "docs"
+
+	// docStubBranchPrefix prefixes the branches placeholder documents are committed to, which are
+	// followed by the ID of the marked post.
+	docStubBranchPrefix = "docup/"
+
+	// maxDocStubSlugLength bounds the length of the file names of placeholder documents.
+	maxDocStubSlugLength = 50
+)
+
+var docStubSlugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// docStubSlug returns the file name, without extension, of the placeholder document for a post
+// with the given title, or an empty string if the title has no usable characters.
+func docStubSlug(title string) string {
+	slug := strings.Trim(docStubSlugPattern.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > maxDocStubSlugLength {
+		slug = strings.TrimRight(slug[:maxDocStubSlugLength], "-")
+	}
+	return slug
+}
+
+// createDocStub opens a pull request adding a placeholder document holding the issue body to the
+// configured docs directory, on a new branch off the default branch of the repository. The pull
+// request is returned as an issue, since GitHub numbers them alike.
+func (p *Plugin) createDocStub(ctx context.Context, client *github.Client, owner, repo, postID string, issueRequest *github.IssueRequest) (*github.Issue, error) {
+	repository, _, err := client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get repository")
+	}
+	base := repository.GetDefaultBranch()
+
+	baseRef, _, err := client.Git.GetRef(ctx, owner, repo, "heads/"+base)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get branch %s", base)
+	}
+
+	branch := docStubBranchPrefix + postID
+	if _, _, err = client.Git.CreateRef(ctx, owner, repo, &github.Reference{
+		Ref:    NewString("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: baseRef.GetObject().SHA},
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to create branch %s", branch)
+	}
+
+	slug := docStubSlug(issueRequest.GetTitle())
+	if slug == "" {
+		slug = postID
+	}
+	filePath := path.Join(p.getConfiguration().getDocsDirectory(), slug+".md")
+	if _, _, err = client.Repositories.CreateFile(ctx, owner, repo, filePath, &github.RepositoryContentFileOptions{
+		Message: NewString("Add placeholder for " + issueRequest.GetTitle()),
+		Content: []byte("# " + issueRequest.GetTitle() + "\n\n" + issueRequest.GetBody() + "\n"),
+		Branch:  NewString(branch),
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", filePath)
+	}
+
+	pullRequest, _, err := client.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
+		Title: issueRequest.Title,
+		Head:  NewString(branch),
+		Base:  NewString(base),
+		Body:  issueRequest.Body,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create pull request")
+	}
+
+	return &github.Issue{
+		ID:      pullRequest.ID,
+		Number:  pullRequest.Number,
+		Title:   pullRequest.Title,
+		HTMLURL: pullRequest.HTMLURL,
+	}, nil
+}
+
+// labelDocStub adds the labels and assignees of the issue request to the documentation stub pull
+// request with the given number. Failures are logged rather than returned, as the pull request
+// has already been opened.
+func (p *Plugin) labelDocStub(ctx context.Context, client *github.Client, owner, repo string, number int, issueRequest *github.IssueRequest, logFields []interface{}) {
+	if issueRequest.Labels == nil && issueRequest.Assignees == nil {
+		return
+	}
+
+	if _, _, err := client.Issues.Edit(ctx, owner, repo, number, &github.IssueRequest{
+		Labels:    issueRequest.Labels,
+		Assignees: issueRequest.Assignees,
+	}); err != nil {
+		p.API.LogWarn("Unable to label documentation stub pull request", withLogFields(logFields, "error", err.Error())...)
+	}
+}
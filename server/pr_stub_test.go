@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDocStubSlug(t *testing.T) {
+	for title, expected := range map[string]string{
+		"Request for Documentation: How to configure SAML?": "request-for-documentation-how-to-configure-saml",
+		"  Spaces   and --- dashes ":                        "spaces-and-dashes",
+		"???":                                               "",
+		"A very long title that goes on and on well past the limit": "a-very-long-title-that-goes-on-and-on-well-past-th",
+	} {
+		assert.Equal(t, expected, docStubSlug(title), title)
+	}
+}
+
+func TestCreateDocStub(t *testing.T) {
+	for name, tc := range map[string]struct {
+		CreateFileStatus int
+		ExpectedURL      string
+	}{
+		"pull request opened": {
+			CreateFileStatus: http.StatusCreated,
+			ExpectedURL:      "https://github.com/owner/repo/pull/2",
+		},
+		"falls back to an issue": {
+			CreateFileStatus: http.StatusUnprocessableEntity,
+			ExpectedURL:      "https://github.com/owner/repo/issues/1",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &plugintest.API{}
+			api.On("GetUser", "user1").Return(&model.User{Id: "user1", Username: "user1"}, nil)
+			api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: NewString("https://mattermost.example.com")}})
+			api.On("GetPost", "post1").Return(&model.Post{Id: "post1", ChannelId: "channel1", Message: "message"}, nil)
+			api.On("HasPermissionToChannel", "user1", "channel1", model.PERMISSION_READ_CHANNEL).Return(true)
+			api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", DisplayName: "Channel"}, nil)
+			api.On("GetTeam", "team1").Return(&model.Team{Id: "team1", DisplayName: "Team"}, nil)
+			api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil)
+			api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+			api.On("KVGet", "history_user1").Return(nil, nil)
+			api.On("AddReaction", mock.Anything).Return(&model.Reaction{}, nil)
+			if tc.CreateFileStatus != http.StatusCreated {
+				api.On("LogWarn", logArguments(5)...).Return()
+			}
+			defer api.AssertExpectations(t)
+
+			plugin := Plugin{botUserID: "bot1"}
+			plugin.SetAPI(api)
+			plugin.setConfiguration(&configuration{AdminRepository: "owner/repo", Mode: modePRStub, DocsDirectory: "/guides/"})
+			plugin.github = newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method + " " + r.URL.Path {
+				case "GET /repos/owner/repo":
+					_, _ = w.Write([]byte(`{"default_branch": "main"}`))
+				case "GET /repos/owner/repo/git/refs/heads/main":
+					_, _ = w.Write([]byte(`{"ref": "refs/heads/main", "object": {"sha": "abc123"}}`))
+				case "POST /repos/owner/repo/git/refs":
+					var ref struct {
+						Ref string `json:"ref"`
+						SHA string `json:"sha"`
+					}
+					assert.Nil(json.NewDecoder(r.Body).Decode(&ref))
+					assert.Equal("refs/heads/docup/post1", ref.Ref)
+					assert.Equal("abc123", ref.SHA)
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{}`))
+				case "PUT /repos/owner/repo/contents/guides/request-for-documentation-title.md":
+					var options github.RepositoryContentFileOptions
+					assert.Nil(json.NewDecoder(r.Body).Decode(&options))
+					assert.Equal("docup/post1", options.GetBranch())
+					assert.Contains(string(options.Content), "# Request for Documentation: title\n\n")
+					w.WriteHeader(tc.CreateFileStatus)
+					_, _ = w.Write([]byte(`{}`))
+				case "POST /repos/owner/repo/pulls":
+					var pullRequest github.NewPullRequest
+					assert.Nil(json.NewDecoder(r.Body).Decode(&pullRequest))
+					assert.Equal("docup/post1", pullRequest.GetHead())
+					assert.Equal("main", pullRequest.GetBase())
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"id": 200, "number": 2, "html_url": "https://github.com/owner/repo/pull/2"}`))
+				case "PATCH /repos/owner/repo/issues/2":
+					var issueRequest github.IssueRequest
+					assert.Nil(json.NewDecoder(r.Body).Decode(&issueRequest))
+					assert.Equal([]string{"docup"}, *issueRequest.Labels)
+					_, _ = w.Write([]byte(`{}`))
+				case "POST /repos/owner/repo/issues":
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"id": 100, "number": 1, "html_url": "https://github.com/owner/repo/issues/1"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/create", bytes.NewBufferString(`{"type":"admin","title":"title","body":"message","post_id":"post1"}`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Mattermost-User-ID", "user1")
+
+			plugin.ServeHTTP(nil, w, r)
+
+			assert.Equal(http.StatusCreated, w.Result().StatusCode)
+			var createResponse CreateAPIResponse
+			assert.Nil(json.NewDecoder(w.Body).Decode(&createResponse))
+			assert.Equal(tc.ExpectedURL, createResponse.IssueURL)
+		})
+	}
+}
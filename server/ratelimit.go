@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// RateLimitStatusAPIResponse reports the GitHub rate limits of the shared GitHub client.
+type RateLimitStatusAPIResponse struct {
+	Core   *RateLimitStatus `json:"core"`
+	Search *RateLimitStatus `json:"search"`
+}
+
+// RateLimitStatus is the state of one of GitHub's rate limits.
+type RateLimitStatus struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+func newRateLimitStatus(rate *github.Rate) *RateLimitStatus {
+	if rate == nil {
+		return nil
+	}
+	return &RateLimitStatus{
+		Limit:     rate.Limit,
+		Remaining: rate.Remaining,
+		Reset:     rate.Reset.Time,
+	}
+}
+
+// handleRateLimit serves the GitHub rate limits of the shared GitHub client to system admins, to
+// help diagnose failures to create issues.
+func (p *Plugin) handleRateLimit(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM) {
+		http.Error(w, "Only system admins can view rate limits", http.StatusForbidden)
+		return
+	}
+
+	if !p.getConfiguration().isGitHub() {
+		http.Error(w, "Only available when issues are filed on GitHub", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	rateLimits, _, err := p.getGitHubClient().RateLimits(ctx)
+	if err != nil {
+		p.API.LogError("Unable to get GitHub rate limits", "user_id", userID, "error", err.Error())
+		http.Error(w, "Unable to get the rate limits from GitHub", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&RateLimitStatusAPIResponse{
+		Core:   newRateLimitStatus(rateLimits.Core),
+		Search: newRateLimitStatus(rateLimits.Search),
+	}); err != nil {
+		p.API.LogError("Unable to encode JSON", "user_id", userID, "error", err.Error())
+	}
+}
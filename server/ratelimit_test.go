@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", "admin1", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	api.On("HasPermissionTo", "user1", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+	defer api.AssertExpectations(t)
+
+	plugin := Plugin{}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{})
+	plugin.github = newTestGitHubClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rate_limit" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"resources": {"core": {"limit": 5000, "remaining": 4990, "reset": 1500000000}, "search": {"limit": 30, "remaining": 0, "reset": 1500000060}}}`))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/ratelimit", nil)
+	plugin.ServeHTTP(nil, w, r)
+	assert.Equal(http.StatusUnauthorized, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/ratelimit", nil)
+	r.Header.Set("Mattermost-User-ID", "user1")
+	plugin.ServeHTTP(nil, w, r)
+	assert.Equal(http.StatusForbidden, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/ratelimit", nil)
+	r.Header.Set("Mattermost-User-ID", "admin1")
+	plugin.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	assert.Equal(http.StatusOK, result.StatusCode)
+
+	var response RateLimitStatusAPIResponse
+	assert.Nil(json.NewDecoder(result.Body).Decode(&response))
+	if assert.NotNil(response.Core) {
+		assert.Equal(5000, response.Core.Limit)
+		assert.Equal(4990, response.Core.Remaining)
+		assert.True(time.Unix(1500000000, 0).Equal(response.Core.Reset))
+	}
+	if assert.NotNil(response.Search) {
+		assert.Equal(30, response.Search.Limit)
+		assert.Equal(0, response.Search.Remaining)
+		assert.True(time.Unix(1500000060, 0).Equal(response.Search.Reset))
+	}
+}
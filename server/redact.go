@@ -0,0 +1,63 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// redactedSecret replaces the secrets found in issue bodies when RedactSecrets is enabled.
+const redactedSecret = "[REDACTED]"
+
+// defaultRedactPatterns match the formats of common secrets. They are always redacted when
+// RedactSecrets is enabled, along with any RedactPatterns.
+var defaultRedactPatterns = []string{
+	// GitHub personal access, OAuth, user-to-server, server-to-server and refresh tokens.
+	`\bgh[pousr]_[A-Za-z0-9]{36,255}\b`,
+	// GitHub fine-grained personal access tokens.
+	`\bgithub_pat_[A-Za-z0-9_]{22,255}\b`,
+	// AWS access key IDs.
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	// Slack tokens.
+	`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`,
+	// Google API keys.
+	`\bAIza[0-9A-Za-z_-]{35}\b`,
+	// Stripe live secret and restricted keys.
+	`\b[rs]k_live_[0-9A-Za-z]{24,}\b`,
+	// PEM encoded private keys.
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// compileRedactPatterns compiles defaultRedactPatterns followed by RedactPatterns, skipping blank
+// lines.
+func (c *configuration) compileRedactPatterns() ([]*regexp.Regexp, error) {
+	patterns := []*regexp.Regexp{}
+	for _, pattern := range defaultRedactPatterns {
+		patterns = append(patterns, regexp.MustCompile(pattern))
+	}
+
+	for _, line := range strings.Split(c.RedactPatterns, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "RedactPatterns entry %q is not a valid regular expression", line)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// redactSecrets replaces the matches of the compiled redaction patterns in the given text with
+// redactedSecret, if RedactSecrets is enabled.
+func (c *configuration) redactSecrets(text string) string {
+	if !c.RedactSecrets {
+		return text
+	}
+	for _, pattern := range c.redactPatterns {
+		text = pattern.ReplaceAllString(text, redactedSecret)
+	}
+	return text
+}
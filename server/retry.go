@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	// retryInitialBackoff is the delay before the first retry; it doubles on each further retry.
+	retryInitialBackoff = 500 * time.Millisecond
+
+	// retryMaxBackoff bounds the delay between two attempts.
+	retryMaxBackoff = 5 * time.Second
+
+	// defaultAbuseRetryAfter is how long to wait after reaching GitHub's secondary rate limit when
+	// GitHub does not say.
+	defaultAbuseRetryAfter = time.Minute
+)
+
+// withRetry calls fn up to maxAttempts times, backing off exponentially between attempts, for as
+// long as it fails with a transient error. When GitHub's secondary rate limit is reached, it waits
+// as long as GitHub asks if that is no longer than retryMaxBackoff, and gives up otherwise. It
+// gives up early if ctx is done.
+func withRetry(ctx context.Context, maxAttempts int, fn func() error) error {
+	backoff := retryInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxAttempts {
+			return err
+		}
+
+		delay := backoff
+		if abuseErr, ok := err.(*github.AbuseRateLimitError); ok {
+			retryAfter := abuseErr.GetRetryAfter()
+			if abuseErr.RetryAfter == nil || retryAfter > retryMaxBackoff {
+				return err
+			}
+			delay = retryAfter
+		} else if !isTransientError(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		backoff *= 2
+		if backoff > retryMaxBackoff {
+			backoff = retryMaxBackoff
+		}
+	}
+}
+
+// isTransientError reports whether a failed issue tracker call is worth retrying: server errors and
+// network failures are, while client errors, rate limits and anything else, such as a response that
+// could not be decoded, are not.
+func isTransientError(err error) bool {
+	switch err := err.(type) {
+	case *github.ErrorResponse:
+		return err.Response != nil && err.Response.StatusCode >= http.StatusInternalServerError
+	case *github.RateLimitError, *github.AbuseRateLimitError, *github.AcceptedError:
+		return false
+	case *gitlabError:
+		return err.StatusCode >= http.StatusInternalServerError
+	case *giteaError:
+		return err.StatusCode >= http.StatusInternalServerError
+	case *url.Error:
+		return err.Err != context.Canceled && err.Err != context.DeadlineExceeded
+	case net.Error:
+		return true
+	}
+
+	return false
+}
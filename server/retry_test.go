@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRetry(t *testing.T) {
+	serverError := &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}
+	clientError := &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+
+	t.Run("retries server errors until success", func(t *testing.T) {
+		attempts := 0
+		err := withRetry(context.Background(), 3, func() error {
+			attempts++
+			if attempts < 2 {
+				return serverError
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		attempts := 0
+		err := withRetry(context.Background(), 2, func() error {
+			attempts++
+			return &url.Error{Op: "Post", URL: "https://api.github.com/repos/owner/repo/issues", Err: errors.New("connection reset")}
+		})
+		assert.NotNil(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		attempts := 0
+		err := withRetry(context.Background(), 3, func() error {
+			attempts++
+			return clientError
+		})
+		assert.Equal(t, clientError, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("waits as asked after a short secondary rate limit", func(t *testing.T) {
+		retryAfter := 10 * time.Millisecond
+		attempts := 0
+		started := time.Now()
+		err := withRetry(context.Background(), 3, func() error {
+			attempts++
+			if attempts < 2 {
+				return &github.AbuseRateLimitError{RetryAfter: &retryAfter}
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.True(t, time.Since(started) >= retryAfter)
+		assert.True(t, time.Since(started) < retryInitialBackoff)
+	})
+
+	t.Run("does not wait out a long secondary rate limit", func(t *testing.T) {
+		retryAfter := time.Minute
+		abuseErr := &github.AbuseRateLimitError{RetryAfter: &retryAfter}
+		attempts := 0
+		err := withRetry(context.Background(), 3, func() error {
+			attempts++
+			return abuseErr
+		})
+		assert.Equal(t, abuseErr, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("does not retry undecodable responses", func(t *testing.T) {
+		decodeErr := pkgerrors.Wrap(errors.New("invalid character '<' looking for beginning of value"), "failed to decode Gitea response")
+		attempts := 0
+		err := withRetry(context.Background(), 3, func() error {
+			attempts++
+			return decodeErr
+		})
+		assert.Equal(t, decodeErr, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("stops when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		attempts := 0
+		err := withRetry(ctx, 3, func() error {
+			attempts++
+			return serverError
+		})
+		assert.Equal(t, serverError, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestIsTransientError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err      error
+		expected bool
+	}{
+		"server error":        {&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}, true},
+		"client error":        {&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}, false},
+		"GitLab server error": {&gitlabError{StatusCode: http.StatusServiceUnavailable}, true},
+		"network failure":     {&url.Error{Op: "Post", URL: "https://gitlab.example.com", Err: errors.New("connection refused")}, true},
+		"timeout":             {&net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		"cancelled request":   {&url.Error{Op: "Post", URL: "https://gitlab.example.com", Err: context.Canceled}, false},
+		"cancelled context":   {context.Canceled, false},
+		"decode failure":      {pkgerrors.Wrap(errors.New("unexpected EOF"), "failed to decode GitLab issue"), false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isTransientError(tc.err))
+		})
+	}
+}
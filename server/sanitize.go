@@ -0,0 +1,49 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches @username mentions and ~channel links that are not part of a longer
+// word, such as an email address.
+var mentionPattern = regexp.MustCompile(`(^|[^\w@~])[@~]([A-Za-z0-9][A-Za-z0-9._-]*)`)
+
+// sanitizeMentions turns @username mentions and ~channel links into plain text by dropping their
+// leading sigil, so that they do not notify unrelated GitHub users or render as noise. Code blocks
+// and inline code are left untouched.
+func sanitizeMentions(body string) string {
+	return replaceOutsideCode(body, func(text string) string {
+		return mentionPattern.ReplaceAllString(text, "$1$2")
+	})
+}
+
+// replaceOutsideCode applies replace to the parts of a markdown body outside of code blocks and
+// inline code.
+func replaceOutsideCode(body string, replace func(string) string) string {
+	lines := strings.Split(body, "\n")
+
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		// Splitting on backticks leaves inline code in the odd segments.
+		segments := strings.Split(line, "`")
+		for j := 0; j < len(segments); j += 2 {
+			segments[j] = replace(segments[j])
+		}
+		lines[i] = strings.Join(segments, "`")
+	}
+
+	return strings.Join(lines, "\n")
+}
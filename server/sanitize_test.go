@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeMentions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Body     string
+		Expected string
+	}{
+		"plain text": {
+			Body:     "Nothing to see here.",
+			Expected: "Nothing to see here.",
+		},
+		"user mentions": {
+			Body:     "@alice and @bob.smith, can you document this?",
+			Expected: "alice and bob.smith, can you document this?",
+		},
+		"channel links": {
+			Body:     "Discussed in ~town-square and (~off-topic).",
+			Expected: "Discussed in town-square and (off-topic).",
+		},
+		"mixed mentions": {
+			Body:     "@channel see ~developers, thanks @alice!",
+			Expected: "channel see developers, thanks alice!",
+		},
+		"email addresses": {
+			Body:     "Mail alice@example.com about it.",
+			Expected: "Mail alice@example.com about it.",
+		},
+		"inline code": {
+			Body:     "Run `git log @{u}` or `ls ~/docs`, @alice.",
+			Expected: "Run `git log @{u}` or `ls ~/docs`, alice.",
+		},
+		"code blocks": {
+			Body:     "@alice try:\n```\nnpm install @mattermost/types\ncd ~mattermost\n```\nthen ping ~developers",
+			Expected: "alice try:\n```\nnpm install @mattermost/types\ncd ~mattermost\n```\nthen ping developers",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, sanitizeMentions(tc.Body))
+		})
+	}
+}
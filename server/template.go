@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// defaultBodyTemplate renders the issue body when no BodyTemplate is configured.
+const defaultBodyTemplate = "Mattermost user `{{.Username}}`{{if .SiteURL}} from {{.SiteURL}}{{end}} has requested the following be documented from the **{{.ChannelName}}** channel{{if .TeamName}} of the **{{.TeamName}}** team{{end}}:\n\n{{if .Fence}}{{.Fence}}{{.CodeLanguage}}\n{{.Body}}\n{{.Fence}}{{else}}{{.Body}}{{end}}\n{{if .Attachments}}\nThe post has the following attachments:\n{{range .Attachments}}\n* {{if .URL}}[{{.Name}}]({{.URL}}){{else}}{{.Name}}{{end}}{{end}}\n{{end}}{{if .Transcript}}\nThe post is part of the following conversation:\n\n{{.Transcript}}\n{{end}}\n{{if .Permalink}}See the original post [here]({{.Permalink}}).{{end}}{{if .Footer}}\n\n{{.Footer}}{{end}}"
+
+// defaultIssueMarker is the text of the marker of created issues when IssueMarker is not
+// configured.
+const defaultIssueMarker = "docup:v1"
+
+// issueMarkerPattern matches marker texts that GitHub's issue search matches as a single term and
+// that cannot end the HTML comment they are wrapped in.
+var issueMarkerPattern = regexp.MustCompile(`^[A-Za-z0-9:._]+(?:-[A-Za-z0-9:._]+)*$`)
+
+// getIssueMarkerText returns the text of the marker of created issues, which GitHub's issue search
+// matches.
+func (c *configuration) getIssueMarkerText() string {
+	if text := strings.TrimSpace(c.IssueMarker); text != "" {
+		return text
+	}
+	return defaultIssueMarker
+}
+
+// getIssueMarker returns the marker appended to the body of every created issue so that they can
+// be told apart from other issues in the repository. It is an HTML comment, which GitHub does not
+// render.
+func (c *configuration) getIssueMarker() string {
+	return "<!-- " + c.getIssueMarkerText() + " -->"
+}
+
+// issueBodyData holds the variables available to the issue body template.
+type issueBodyData struct {
+	// Type is the documentation type of the issue, which selects its body template.
+	Type string
+
+	Username    string
+	SiteURL     string
+	Body        string
+	Permalink   string
+	ChannelName string
+	TeamName    string
+	Attachments []issueAttachment
+
+	// Footer is the configured footer, computed by renderIssueBody.
+	Footer string
+
+	// Fence is a code fence long enough to wrap Body without being closed by any backticks
+	// inside it, or empty if Body is not to be wrapped. CodeLanguage is the configured language of
+	// the fence. Both are computed by renderIssueBody.
+	Fence        string
+	CodeLanguage string
+
+	// Posts lists the posts of a request filing several posts as one issue. Body is then set by
+	// renderIssueBody to their messages, each in its own code fence and linked to its post.
+	Posts []issuePost
+
+	// ThreadPosts lists the other posts in the thread of the marked post when IncludeThread is
+	// set, and OmittedThreadPosts counts those left out of it by MaxThreadMessages. Transcript is
+	// set by renderIssueBody to their messages, each quoted under its author.
+	ThreadPosts        []issueThreadPost
+	OmittedThreadPosts int
+	Transcript         string
+}
+
+// confirmationData holds the variables available to the confirmation template.
+type confirmationData struct {
+	Permalink   string
+	IssueURL    string
+	IssueNumber int
+	Existing    bool
+	Footer      string
+}
+
+// issuePost is one of several posts filed as a single issue.
+type issuePost struct {
+	Permalink string
+	Body      string
+}
+
+// issueThreadPost is a post in the thread of the marked post.
+type issueThreadPost struct {
+	Username string
+	Body     string
+}
+
+// issueAttachment describes a file attached to the marked post. URL is empty when the issue body
+// does not link to the Mattermost server.
+type issueAttachment struct {
+	Name string
+	URL  string
+}
+
+// truncatedSuffix is appended to post bodies shortened by truncateBody.
+const truncatedSuffix = "...(truncated)\n\nThis post was too long to include in full."
+
+// truncateBody shortens body to at most maxLength characters, appending truncatedSuffix if
+// anything was removed. Characters are counted as runes so multibyte characters are never split.
+func truncateBody(body string, maxLength int) (string, bool) {
+	runes := []rune(body)
+	if len(runes) <= maxLength {
+		return body, false
+	}
+	return string(runes[:maxLength]) + truncatedSuffix, true
+}
+
+// truncateTitle shortens title to at most maxLength characters, ending it with an ellipsis if
+// anything was removed. Characters are counted as runes so multibyte characters are never split.
+func truncateTitle(title string, maxLength int) (string, bool) {
+	runes := []rune(title)
+	if len(runes) <= maxLength {
+		return title, false
+	}
+	return strings.TrimRightFunc(string(runes[:maxLength-1]), unicode.IsSpace) + "…", true
+}
+
+// codeFence returns a backtick code fence longer than any run of backticks in body, and at least
+// three backticks long.
+func codeFence(body string) string {
+	longest, run := 0, 0
+	for _, r := range body {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// parseBodyTemplate parses the body template of the given issue type from TypeBodyTemplateMap,
+// falling back to BodyTemplate and then to defaultBodyTemplate.
+func (c *configuration) parseBodyTemplate(issueType string) (*template.Template, error) {
+	typeBodyTemplates, err := c.parseTypeBodyTemplateMap()
+	if err != nil {
+		return nil, err
+	}
+	if text := typeBodyTemplates[issueType]; strings.TrimSpace(text) != "" {
+		tmpl, err := template.New("body").Parse(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse TypeBodyTemplateMap entry for type %s", issueType)
+		}
+		return tmpl, nil
+	}
+
+	text := c.BodyTemplate
+	if text == "" {
+		text = defaultBodyTemplate
+	}
+
+	tmpl, err := template.New("body").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse BodyTemplate")
+	}
+	return tmpl, nil
+}
+
+// issueTitleData holds the variables available to the issue title templates.
+type issueTitleData struct {
+	Title       string
+	Type        string
+	Username    string
+	ChannelName string
+	TeamName    string
+}
+
+// parseTitleTemplate parses the title template of the given issue type from
+// TypeTitleTemplateMap, returning nil if it has none.
+func (c *configuration) parseTitleTemplate(issueType string) (*template.Template, error) {
+	typeTitleTemplates, err := c.parseTypeTitleTemplateMap()
+	if err != nil {
+		return nil, err
+	}
+	text := typeTitleTemplates[issueType]
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("title").Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse TypeTitleTemplateMap entry for type %s", issueType)
+	}
+	return tmpl, nil
+}
+
+// renderIssueTitle executes the title template of the issue type with the given data, or
+// prepends the title prefix of the type to the requested title if it has no template or renders
+// an empty title. The title is not truncated.
+func (c *configuration) renderIssueTitle(data *issueTitleData) (string, error) {
+	tmpl, err := c.parseTitleTemplate(data.Type)
+	if err != nil {
+		return "", err
+	}
+	if tmpl == nil {
+		return c.getTitlePrefix(data.Type) + data.Title, nil
+	}
+
+	var title bytes.Buffer
+	if err := tmpl.Execute(&title, data); err != nil {
+		return "", errors.Wrap(err, "failed to execute title template")
+	}
+	if rendered := strings.TrimSpace(title.String()); rendered != "" {
+		return rendered, nil
+	}
+	return c.getTitlePrefix(data.Type) + data.Title, nil
+}
+
+// renderIssueBody executes the issue body template with the given data, and appends a mention of
+// the NotifyTeam, if any, and the issue marker.
+func (c *configuration) renderIssueBody(data *issueBodyData) (string, error) {
+	tmpl, err := c.parseBodyTemplate(data.Type)
+	if err != nil {
+		return "", err
+	}
+
+	fencedData := *data
+	if !c.includePermalink() {
+		fencedData.SiteURL = ""
+		fencedData.Permalink = ""
+		fencedData.Posts = make([]issuePost, len(data.Posts))
+		for i, post := range data.Posts {
+			fencedData.Posts[i] = issuePost{Body: post.Body}
+		}
+		fencedData.Attachments = make([]issueAttachment, len(data.Attachments))
+		for i, attachment := range data.Attachments {
+			fencedData.Attachments[i] = issueAttachment{Name: attachment.Name}
+		}
+	}
+	if len(fencedData.Posts) > 0 {
+		fencedData.Body = c.joinPosts(fencedData.Posts)
+	} else if c.wrapBodyInCodeFence() {
+		fencedData.Fence = codeFence(data.Body)
+		fencedData.CodeLanguage = strings.TrimSpace(c.BodyCodeLanguage)
+	}
+	if len(data.ThreadPosts) > 0 {
+		fencedData.Transcript = threadTranscript(data.ThreadPosts, data.OmittedThreadPosts)
+	}
+	fencedData.Footer = c.getIssueFooter()
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, &fencedData); err != nil {
+		return "", errors.Wrap(err, "failed to execute body template")
+	}
+	if team := strings.TrimSpace(c.NotifyTeam); team != "" {
+		body.WriteString("\n\ncc " + team)
+	}
+	return body.String() + "\n\n" + c.getIssueMarker(), nil
+}
+
+// parseConfirmationTemplate parses the configured ConfirmationTemplate, returning nil if none is
+// configured.
+func (c *configuration) parseConfirmationTemplate() (*template.Template, error) {
+	if c.ConfirmationTemplate == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("confirmation").Parse(c.ConfirmationTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse ConfirmationTemplate")
+	}
+	return tmpl, nil
+}
+
+// renderConfirmation executes the confirmation template with the given data. It returns false if
+// no ConfirmationTemplate is configured, in which case the default message is to be used.
+func (c *configuration) renderConfirmation(data *confirmationData) (string, bool, error) {
+	tmpl, err := c.parseConfirmationTemplate()
+	if err != nil || tmpl == nil {
+		return "", false, err
+	}
+
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, data); err != nil {
+		return "", false, errors.Wrap(err, "failed to execute ConfirmationTemplate")
+	}
+	return message.String(), true, nil
+}
+
+// joinPosts renders the messages of several posts filed as one issue, each introduced by a link
+// to its post, if known, and wrapped in a code fence unless WrapBodyInCodeFence is false.
+func (c *configuration) joinPosts(posts []issuePost) string {
+	rendered := make([]string, 0, len(posts))
+	for i, post := range posts {
+		heading := fmt.Sprintf("Post %d:", i+1)
+		if post.Permalink != "" {
+			heading = fmt.Sprintf("[Post %d](%s):", i+1, post.Permalink)
+		}
+
+		body := post.Body
+		if c.wrapBodyInCodeFence() {
+			fence := codeFence(body)
+			body = fence + strings.TrimSpace(c.BodyCodeLanguage) + "\n" + body + "\n" + fence
+		}
+		rendered = append(rendered, heading+"\n"+body)
+	}
+	return strings.Join(rendered, "\n\n")
+}
+
+// threadTranscript renders the posts of a thread, each quoted under the username of its author,
+// noting the number of posts omitted, if any.
+func threadTranscript(posts []issueThreadPost, omitted int) string {
+	rendered := make([]string, 0, len(posts)+1)
+	for _, post := range posts {
+		lines := strings.Split(post.Body, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		rendered = append(rendered, "**"+post.Username+"**:\n"+strings.Join(lines, "\n"))
+	}
+	if omitted == 1 {
+		rendered = append(rendered, "_1 more post in the thread was left out._")
+	} else if omitted > 1 {
+		rendered = append(rendered, fmt.Sprintf("_%d more posts in the thread were left out._", omitted))
+	}
+	return strings.Join(rendered, "\n\n")
+}
+
+// sanitizeMessage redacts secrets from and sanitizes the message of a post as configured.
+func (c *configuration) sanitizeMessage(message string) string {
+	message = c.redactSecrets(message)
+	if c.SanitizeMentions {
+		message = sanitizeMentions(message)
+	}
+	if c.ConvertEmoji {
+		message = convertEmoji(message)
+	}
+	return message
+}
+
+// preparePostBody redacts secrets from and sanitizes the message of a post as configured, and
+// truncates it to MaxBodyLength characters, returning it with the number of characters kept and
+// whether any were removed.
+func (c *configuration) preparePostBody(message string) (string, int, bool) {
+	message = c.sanitizeMessage(message)
+
+	maxBodyLength := c.getMaxBodyLength()
+	length := utf8.RuneCountInString(message)
+	message, truncated := truncateBody(message, maxBodyLength)
+	if truncated {
+		length = maxBodyLength
+	}
+	return message, length, truncated
+}
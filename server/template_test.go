@@ -0,0 +1,333 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderIssueBodyWithBackticks(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Body          string
+		ExpectedFence string
+	}{
+		"no backticks": {
+			Body:          "How do I configure SAML?",
+			ExpectedFence: "```",
+		},
+		"inline code": {
+			Body:          "Run `mattermost version` to check.",
+			ExpectedFence: "```",
+		},
+		"code block": {
+			Body:          "Try this:\n```\nmattermost version\n```",
+			ExpectedFence: "````",
+		},
+		"longer code block": {
+			Body:          "`````\nnested ``` fence\n`````",
+			ExpectedFence: "``````",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{}
+			body, err := config.renderIssueBody(&issueBodyData{
+				Username:    "user",
+				SiteURL:     "https://example.com",
+				Body:        tc.Body,
+				Permalink:   "https://example.com/_redirect/pl/post1",
+				ChannelName: "Town Square",
+			})
+			require.NoError(t, err)
+
+			assert.Contains(t, body, "\n\n"+tc.ExpectedFence+"\n"+tc.Body+"\n"+tc.ExpectedFence+"\n\n")
+			assert.Contains(t, body, "See the original post [here](https://example.com/_redirect/pl/post1).")
+			assert.Contains(t, body, "using the [Doc Up](https://github.com/jwilander/mattermost-plugin-docup) plugin._")
+			assert.True(t, strings.HasSuffix(body, "\n\n<!-- docup:v1 -->"))
+		})
+	}
+}
+
+func TestRenderIssueBodyCodeFence(t *testing.T) {
+	wrap, noWrap := true, false
+
+	for name, tc := range map[string]struct {
+		BodyCodeLanguage    string
+		WrapBodyInCodeFence *bool
+		Expected            string
+	}{
+		"default": {
+			Expected: "\n\n```\nSome *prose*.\n```\n\n",
+		},
+		"language": {
+			BodyCodeLanguage:    "markdown",
+			WrapBodyInCodeFence: &wrap,
+			Expected:            "\n\n```markdown\nSome *prose*.\n```\n\n",
+		},
+		"not wrapped": {
+			BodyCodeLanguage:    "markdown",
+			WrapBodyInCodeFence: &noWrap,
+			Expected:            ":\n\nSome *prose*.\n\nSee the original post",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{BodyCodeLanguage: tc.BodyCodeLanguage, WrapBodyInCodeFence: tc.WrapBodyInCodeFence}
+			body, err := config.renderIssueBody(&issueBodyData{
+				Username:    "user",
+				SiteURL:     "https://example.com",
+				Body:        "Some *prose*.",
+				Permalink:   "https://example.com/_redirect/pl/post1",
+				ChannelName: "Town Square",
+			})
+			require.NoError(t, err)
+
+			assert.Contains(t, body, tc.Expected)
+		})
+	}
+}
+
+func TestRenderIssueBodyPermalink(t *testing.T) {
+	include, exclude := true, false
+
+	for name, tc := range map[string]struct {
+		IncludePermalink *bool
+		Expected         bool
+	}{
+		"default":  {Expected: true},
+		"included": {IncludePermalink: &include, Expected: true},
+		"excluded": {IncludePermalink: &exclude, Expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &configuration{IncludePermalink: tc.IncludePermalink}
+			body, err := config.renderIssueBody(&issueBodyData{
+				Username:    "user",
+				SiteURL:     "https://chat.internal.example.com",
+				Body:        "Some *prose*.",
+				Permalink:   "https://chat.internal.example.com/_redirect/pl/post1",
+				ChannelName: "Town Square",
+				Attachments: []issueAttachment{{Name: "screenshot.png", URL: "https://chat.internal.example.com/api/v4/files/file1"}},
+			})
+			require.NoError(t, err)
+
+			assert.Contains(t, body, "Mattermost user `user`")
+			assert.Contains(t, body, "Some *prose*.")
+			if tc.Expected {
+				assert.Contains(t, body, "from https://chat.internal.example.com has requested")
+				assert.Contains(t, body, "See the original post [here](https://chat.internal.example.com/_redirect/pl/post1).")
+				assert.Contains(t, body, "* [screenshot.png](https://chat.internal.example.com/api/v4/files/file1)")
+			} else {
+				assert.NotContains(t, body, "chat.internal.example.com")
+				assert.Contains(t, body, "* screenshot.png")
+				assert.NotContains(t, body, "See the original post")
+			}
+		})
+	}
+}
+
+func TestRenderIssueBodyNotifyTeam(t *testing.T) {
+	data := &issueBodyData{Username: "user", Body: "Some *prose*.", ChannelName: "Town Square"}
+
+	body, err := (&configuration{NotifyTeam: " @org/docs-team "}).renderIssueBody(data)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(body, "\n\ncc @org/docs-team\n\n<!-- docup:v1 -->"), body)
+
+	body, err = (&configuration{}).renderIssueBody(data)
+	require.NoError(t, err)
+	assert.NotContains(t, body, "cc @")
+}
+
+func TestRenderIssueBodyThread(t *testing.T) {
+	data := &issueBodyData{
+		Username:    "user",
+		Body:        "Some *prose*.",
+		ChannelName: "Town Square",
+		ThreadPosts: []issueThreadPost{
+			{Username: "alice", Body: "How do I enable SAML?\n\nThe docs are unclear."},
+			{Username: "bob", Body: "See the System Console."},
+		},
+		OmittedThreadPosts: 2,
+	}
+
+	body, err := (&configuration{}).renderIssueBody(data)
+	require.NoError(t, err)
+	assert.Contains(t, body, "Some *prose*.\n```\n\nThe post is part of the following conversation:\n\n**alice**:\n> How do I enable SAML?\n>\n> The docs are unclear.\n\n**bob**:\n> See the System Console.\n\n_2 more posts in the thread were left out._\n")
+
+	data.ThreadPosts = nil
+	body, err = (&configuration{}).renderIssueBody(data)
+	require.NoError(t, err)
+	assert.NotContains(t, body, "conversation")
+}
+
+func TestRenderIssueBodyMarker(t *testing.T) {
+	data := &issueBodyData{Username: "user", Body: "Some *prose*.", ChannelName: "Town Square"}
+
+	body, err := (&configuration{IssueMarker: " docs-team:v2 "}).renderIssueBody(data)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(body, "\n\n<!-- docs-team:v2 -->"), body)
+	assert.NotContains(t, body, defaultIssueMarker)
+}
+
+func TestRenderIssueBodyTypeTemplate(t *testing.T) {
+	config := &configuration{
+		BodyTemplate:        "Global: {{.Body}}",
+		TypeBodyTemplateMap: `{"handbook": "Handbook request from {{.Username}}: {{.Body}}"}`,
+	}
+
+	for issueType, expected := range map[string]string{
+		"handbook": "Handbook request from user: message",
+		"admin":    "Global: message",
+	} {
+		body, err := config.renderIssueBody(&issueBodyData{Type: issueType, Username: "user", Body: "message"})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(body, expected), body)
+	}
+
+	body, err := (&configuration{TypeBodyTemplateMap: `{"handbook": "Handbook: {{.Body}}"}`}).renderIssueBody(&issueBodyData{Type: "admin", Username: "user", Body: "message"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(body, "Mattermost user `user`"), "types without a template should fall back to the default body")
+}
+
+func TestRenderIssueTitle(t *testing.T) {
+	config := &configuration{
+		TitlePrefix:          "[Docs]",
+		TypeTitleTemplateMap: `{"admin": "[Admin] {{.Title}} ({{.ChannelName}})", "handbook": "{{if false}}never{{end}}"}`,
+	}
+
+	for issueType, expected := range map[string]string{
+		"admin":     "[Admin] How to configure SAML? (Town Square)",
+		"developer": "[Docs] How to configure SAML?",
+		"handbook":  "[Docs] How to configure SAML?",
+	} {
+		title, err := config.renderIssueTitle(&issueTitleData{Title: "How to configure SAML?", Type: issueType, ChannelName: "Town Square"})
+		require.NoError(t, err)
+		assert.Equal(t, expected, title, issueType)
+	}
+}
+
+func TestRenderConfirmation(t *testing.T) {
+	data := &confirmationData{
+		Permalink:   "https://example.com/_redirect/pl/post1",
+		IssueURL:    "https://github.com/owner/repo/issues/1",
+		IssueNumber: 1,
+		Footer:      "_footer_",
+	}
+
+	t.Run("not configured", func(t *testing.T) {
+		message, ok, err := (&configuration{}).renderConfirmation(data)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Empty(t, message)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		config := &configuration{ConfirmationTemplate: "{{if .Existing}}Updated{{else}}Filed{{end}} [#{{.IssueNumber}}]({{.IssueURL}}) for [this post]({{.Permalink}}).\n{{.Footer}}"}
+		message, ok, err := config.renderConfirmation(data)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "Filed [#1](https://github.com/owner/repo/issues/1) for [this post](https://example.com/_redirect/pl/post1).\n_footer_", message)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, ok, err := (&configuration{ConfirmationTemplate: "{{.Missing.Field}}"}).renderConfirmation(data)
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestTruncateBody(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Body              string
+		MaxLength         int
+		ExpectedBody      string
+		ExpectedTruncated bool
+	}{
+		"shorter than limit": {
+			Body:              "short",
+			MaxLength:         10,
+			ExpectedBody:      "short",
+			ExpectedTruncated: false,
+		},
+		"exactly the limit": {
+			Body:              "exactly",
+			MaxLength:         7,
+			ExpectedBody:      "exactly",
+			ExpectedTruncated: false,
+		},
+		"longer than limit": {
+			Body:              "much too long",
+			MaxLength:         4,
+			ExpectedBody:      "much" + truncatedSuffix,
+			ExpectedTruncated: true,
+		},
+		"multibyte characters are not split": {
+			Body:              "日本語のテキスト",
+			MaxLength:         3,
+			ExpectedBody:      "日本語" + truncatedSuffix,
+			ExpectedTruncated: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, truncated := truncateBody(tc.Body, tc.MaxLength)
+			assert.Equal(t, tc.ExpectedBody, body)
+			assert.Equal(t, tc.ExpectedTruncated, truncated)
+		})
+	}
+}
+
+func TestTruncateTitle(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Title             string
+		MaxLength         int
+		ExpectedTitle     string
+		ExpectedTruncated bool
+	}{
+		"exactly the limit": {
+			Title:             "exactly",
+			MaxLength:         7,
+			ExpectedTitle:     "exactly",
+			ExpectedTruncated: false,
+		},
+		"longer than limit": {
+			Title:             "much too long",
+			MaxLength:         8,
+			ExpectedTitle:     "much to…",
+			ExpectedTruncated: true,
+		},
+		"trailing space is dropped": {
+			Title:             "much too long",
+			MaxLength:         6,
+			ExpectedTitle:     "much…",
+			ExpectedTruncated: true,
+		},
+		"multibyte characters are not split": {
+			Title:             "日本語のテキスト",
+			MaxLength:         4,
+			ExpectedTitle:     "日本語…",
+			ExpectedTruncated: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			title, truncated := truncateTitle(tc.Title, tc.MaxLength)
+			assert.Equal(t, tc.ExpectedTitle, title)
+			assert.Equal(t, tc.ExpectedTruncated, truncated)
+		})
+	}
+}
+
+func TestRenderIssueBodyWithAttachments(t *testing.T) {
+	config := &configuration{}
+	body, err := config.renderIssueBody(&issueBodyData{
+		Username:  "user",
+		SiteURL:   "https://example.com",
+		Body:      "See the screenshot",
+		Permalink: "https://example.com/_redirect/pl/post1",
+		Attachments: []issueAttachment{
+			{Name: "screenshot.png", URL: "https://example.com/api/v4/files/file1"},
+			{Name: "logs.txt", URL: "https://example.com/api/v4/files/file2"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, body, "The post has the following attachments:\n\n* [screenshot.png](https://example.com/api/v4/files/file1)\n* [logs.txt](https://example.com/api/v4/files/file2)\n\nSee the original post")
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// syncThreadReply adds a reply to the issue created for a post in its thread, if any, as a
+// comment on behalf of the replying user. Replies are filtered, sanitized, truncated and linked
+// like marked posts.
+func (p *Plugin) syncThreadReply(post *model.Post) {
+	if post.RootId == "" || strings.TrimSpace(post.Message) == "" {
+		return
+	}
+
+	config := p.getConfiguration()
+	if !config.isGitHub() {
+		return
+	}
+
+	logFields := []interface{}{"user_id", post.UserId, "post_id", post.Id, "root_id", post.RootId}
+
+	issue, err := p.getThreadIssue(post.RootId)
+	if err != nil {
+		p.API.LogError("Unable to get thread issue", withLogFields(logFields, "error", err.Error())...)
+		return
+	}
+	if issue == nil {
+		return
+	}
+
+	logFields = withLogFields(logFields, "repo", issue.Repository, "issue", issue.IssueNumber)
+
+	if config.isBlocked(post.Message) {
+		p.API.LogWarn("Not syncing thread reply matching a blocked pattern", logFields...)
+		return
+	}
+
+	owner, repo, err := splitOwnerAndRepo(issue.Repository)
+	if err != nil {
+		p.API.LogError("Bad thread issue repo", withLogFields(logFields, "error", err.Error())...)
+		return
+	}
+
+	user, appErr := p.API.GetUser(post.UserId)
+	if appErr != nil {
+		p.API.LogError("Unable to get user", withLogFields(logFields, "error", appErr.Error())...)
+		return
+	}
+
+	message, _, _ := config.preparePostBody(post.Message)
+	attribution := fmt.Sprintf("_Replied by Mattermost user `%s` in the thread._", user.Username)
+
+	if config.includePermalink() {
+		siteURL, err := p.getSiteURL()
+		if err != nil {
+			p.API.LogError("Unable to get site URL", withLogFields(logFields, "error", err.Error())...)
+			return
+		}
+		permalink, err := url.Parse(siteURL)
+		if err != nil {
+			p.API.LogError("Unable to parse site URL", withLogFields(logFields, "error", err.Error())...)
+			return
+		}
+		permalink.Path = path.Join(permalink.Path, "_redirect", "pl", post.Id)
+		attribution = fmt.Sprintf("_Replied by Mattermost user `%s` in [this thread](%s)._", user.Username, permalink.String())
+	}
+
+	comment := &github.IssueComment{
+		Body: NewString(message + "\n\n" + attribution),
+	}
+
+	ctx, cancel := p.githubContext()
+	defer cancel()
+
+	if _, _, err := p.getGitHubClientForUser(post.UserId).Issues.CreateComment(ctx, owner, repo, issue.IssueNumber, comment); err != nil {
+		p.API.LogError("Error commenting on GitHub issue", withLogFields(logFields, "error", err.Error())...)
+	}
+}
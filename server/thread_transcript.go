@@ -0,0 +1,68 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// getThreadPosts returns the posts of the thread of the marked post, oldest first, for the
+// transcript included in its issue when IncludeThread is set, with the number of posts left out
+// by MaxThreadMessages. The posts given, which the issue already includes, are skipped, as are
+// deleted posts, system messages and the posts of the bot. Failures are logged rather than
+// returned, as the issue can be filed without the transcript. Messages are sanitized and truncated
+// like the marked post.
+func (p *Plugin) getThreadPosts(config *configuration, rootID string, included []*model.Post, logFields []interface{}) ([]issueThreadPost, int) {
+	postList, appErr := p.API.GetPostThread(rootID)
+	if appErr != nil {
+		p.API.LogWarn("Unable to get post thread", withLogFields(logFields, "root_id", rootID, "error", appErr.Error())...)
+		return nil, 0
+	}
+
+	skipped := map[string]bool{}
+	for _, post := range included {
+		skipped[post.Id] = true
+	}
+
+	posts := []*model.Post{}
+	for _, post := range postList.Posts {
+		if skipped[post.Id] || post.DeleteAt != 0 || post.IsSystemMessage() {
+			continue
+		}
+		if p.botUserID != "" && post.UserId == p.botUserID {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreateAt < posts[j].CreateAt
+	})
+
+	omitted := 0
+	if maxThreadMessages := config.getMaxThreadMessages(); len(posts) > maxThreadMessages {
+		omitted = len(posts) - maxThreadMessages
+		posts = posts[:maxThreadMessages]
+	}
+
+	usernames := map[string]string{}
+	threadPosts := make([]issueThreadPost, 0, len(posts))
+	for _, post := range posts {
+		username, ok := usernames[post.UserId]
+		if !ok {
+			username = "Unknown User"
+			if user, appErr := p.API.GetUser(post.UserId); appErr != nil {
+				p.API.LogWarn("Unable to get thread post author", withLogFields(logFields, "thread_user_id", post.UserId, "error", appErr.Error())...)
+			} else {
+				username = user.Username
+			}
+			usernames[post.UserId] = username
+		}
+
+		body, _ := truncateBody(config.sanitizeMessage(post.Message), config.getMaxBodyLength())
+		threadPosts = append(threadPosts, issueThreadPost{
+			Username: username,
+			Body:     body,
+		})
+	}
+	return threadPosts, omitted
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin"
+)
+
+const (
+	// maxTriggeredTitleLength is the number of characters of a post used as the title of the
+	// issues filed when the trigger emoji is posted.
+	maxTriggeredTitleLength = 80
+
+	// defaultTriggeredTitle is used as the title of triggered issues for posts without text.
+	defaultTriggeredTitle = "Documentation request"
+)
+
+// MessageHasBeenPosted files a documentation issue for a post when someone replies to it with
+// only the configured TriggerEmoji, and otherwise syncs replies to the issue of their thread when
+// SyncThreadReplies is enabled. Reaction hooks are not available on the supported server versions,
+// so a reply stands in for the reaction.
+func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
+	// Ignore the plugin's own posts and those of other bots to avoid loops.
+	if post.UserId == p.botUserID || post.IsSystemMessage() || post.Props["from_bot"] == "true" {
+		return
+	}
+
+	config := p.getConfiguration()
+	emoji := config.getTriggerEmoji()
+	if emoji == "" || strings.TrimSpace(post.Message) != ":"+emoji+":" {
+		if config.SyncThreadReplies {
+			p.syncThreadReply(post)
+		}
+		return
+	}
+
+	postID := post.ParentId
+	if postID == "" {
+		postID = post.RootId
+	}
+	if postID == "" {
+		return
+	}
+
+	docPost, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		p.API.LogError("Unable to get post", "user_id", post.UserId, "post_id", postID, "error", appErr.Error())
+		return
+	}
+
+	issueType := config.DefaultType
+	if issueType == "" {
+		enabledTypes := config.getEnabledTypes()
+		if len(enabledTypes) == 0 {
+			return
+		}
+		issueType = enabledTypes[0]
+	}
+
+	createResponse, err := p.createIssueFromPost(p.pluginContext(), post.UserId, &CreateAPIRequest{
+		Type:   issueType,
+		Title:  titleFromMessage(config.sanitizeMessage(docPost.Message)),
+		Body:   docPost.Message,
+		PostID: docPost.Id,
+	})
+	p.metrics.recordCreate(createResponse, err)
+	if err != nil {
+		p.API.SendEphemeralPost(post.UserId, &model.Post{
+			UserId:    p.botUserID,
+			ChannelId: post.ChannelId,
+			RootId:    post.RootId,
+			Message:   "Unable to create the documentation issue: " + err.Error(),
+		})
+	}
+}
+
+// titleFromMessage derives an issue title from the first line of a post's message.
+func titleFromMessage(message string) string {
+	title := strings.TrimSpace(strings.SplitN(strings.TrimSpace(message), "\n", 2)[0])
+	if title == "" {
+		return defaultTriggeredTitle
+	}
+	title, _ = truncateTitle(title, maxTriggeredTitleLength)
+	return title
+}
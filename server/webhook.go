@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// handleWebhook receives GitHub issue events, replying in the original thread when an issue
+// created by the plugin is closed, unless it was closed from Mattermost as created in error.
+// Payloads must be signed with the configured WebhookSecret.
+func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	secret := p.getConfiguration().WebhookSecret
+	if secret == "" {
+		http.Error(w, "Webhook secret not configured", http.StatusUnauthorized)
+		return
+	}
+
+	payload, err := github.ValidatePayload(r, []byte(secret))
+	if err != nil {
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(r), payload)
+	if err != nil {
+		http.Error(w, "Unable to parse webhook", http.StatusBadRequest)
+		return
+	}
+
+	issuesEvent, ok := event.(*github.IssuesEvent)
+	if !ok || issuesEvent.GetAction() != "closed" || !strings.Contains(issuesEvent.GetIssue().GetBody(), p.getConfiguration().getIssueMarker()) {
+		return
+	}
+
+	repository := issuesEvent.GetRepo().GetFullName()
+	number := issuesEvent.GetIssue().GetNumber()
+
+	mapping, err := p.getIssueMapping(repository, number)
+	if err != nil {
+		p.API.LogError("Unable to get issue mapping", "repo", repository, "issue", number, "error", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if mapping == nil || mapping.ClosedInError {
+		return
+	}
+
+	if p.botUserID == "" {
+		p.API.LogWarn("Unable to reply to documented post without a bot user", "repo", repository, "issue", number, "post_id", mapping.PostID)
+		return
+	}
+
+	if _, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.botUserID,
+		ChannelId: mapping.ChannelID,
+		RootId:    mapping.RootID,
+		ParentId:  mapping.PostID,
+		Message:   fmt.Sprintf("Documentation completed ✅ [#%d](%s)", number, issuesEvent.GetIssue().GetHTMLURL()),
+	}); appErr != nil {
+		p.API.LogError("Unable to create post", "repo", repository, "issue", number, "post_id", mapping.PostID, "error", appErr.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testClosedIssuePayload = `{"action": "closed", "issue": {"number": 1, "html_url": "https://github.com/owner/repo/issues/1", "body": "body\n\n<!-- docup:v1 -->"}, "repository": {"full_name": "owner/repo"}}`
+
+func signPayload(secret, payload string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	_, _ = mac.Write([]byte(payload))
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookRequest(payload, signature string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(payload))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-GitHub-Event", "issues")
+	if signature != "" {
+		r.Header.Set("X-Hub-Signature", signature)
+	}
+	return r
+}
+
+func TestWebhookRejectsBadSignatures(t *testing.T) {
+	for name, tc := range map[string]struct {
+		WebhookSecret string
+		Signature     string
+	}{
+		"no secret configured": {
+			WebhookSecret: "",
+			Signature:     signPayload("", testClosedIssuePayload),
+		},
+		"unsigned": {
+			WebhookSecret: "secret",
+			Signature:     "",
+		},
+		"mismatched signature": {
+			WebhookSecret: "secret",
+			Signature:     signPayload("other", testClosedIssuePayload),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			plugin := Plugin{}
+			plugin.SetAPI(&plugintest.API{})
+			plugin.setConfiguration(&configuration{WebhookSecret: tc.WebhookSecret})
+
+			w := httptest.NewRecorder()
+			plugin.ServeHTTP(nil, w, newWebhookRequest(testClosedIssuePayload, tc.Signature))
+
+			assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestWebhookRepliesWhenIssueClosed(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVGet", "issue_owner/repo/1").Return([]byte(`{"channel_id":"channel1","root_id":"post1","post_id":"post2"}`), nil)
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.UserId == "bot1" && post.ChannelId == "channel1" && post.RootId == "post1" && post.ParentId == "post2" &&
+			strings.HasPrefix(post.Message, "Documentation completed ✅")
+	})).Return(&model.Post{}, nil)
+	defer api.AssertExpectations(t)
+
+	plugin := Plugin{botUserID: "bot1"}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{WebhookSecret: "secret"})
+
+	w := httptest.NewRecorder()
+	plugin.ServeHTTP(nil, w, newWebhookRequest(testClosedIssuePayload, signPayload("secret", testClosedIssuePayload)))
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestWebhookIgnoresIssuesClosedInError(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVGet", "issue_owner/repo/1").Return([]byte(`{"channel_id":"channel1","root_id":"post1","post_id":"post2","closed_in_error":true}`), nil)
+	defer api.AssertExpectations(t)
+
+	plugin := Plugin{botUserID: "bot1"}
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{WebhookSecret: "secret"})
+
+	w := httptest.NewRecorder()
+	plugin.ServeHTTP(nil, w, newWebhookRequest(testClosedIssuePayload, signPayload("secret", testClosedIssuePayload)))
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	api.AssertNotCalled(t, "CreatePost", mock.Anything)
+}
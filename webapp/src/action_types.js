@@ -2,3 +2,4 @@ import {id as pluginId} from './manifest';
 
 export const OPEN_ROOT_MODAL = pluginId + '_open_root_modal';
 export const CLOSE_ROOT_MODAL = pluginId + '_close_root_modal';
+export const RECEIVED_TYPES = pluginId + '_received_types';
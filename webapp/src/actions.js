@@ -1,13 +1,14 @@
 import {getConfig} from 'mattermost-redux/selectors/entities/general';
 
 import {id as pluginId} from './manifest';
-import {OPEN_ROOT_MODAL, CLOSE_ROOT_MODAL} from './action_types';
+import {OPEN_ROOT_MODAL, CLOSE_ROOT_MODAL, RECEIVED_TYPES} from './action_types';
 
 export const openRootModal = (postID) => (dispatch) => {
     dispatch({
         type: OPEN_ROOT_MODAL,
         postID,
     });
+    dispatch(fetchTypes());
 };
 
 export const closeRootModal = () => (dispatch) => {
@@ -32,6 +33,24 @@ export const getPluginServerRoute = (state) => {
     return basePath + '/plugins/' + pluginId;
 };
 
+export const fetchTypes = () => async (dispatch, getState) => {
+    const response = await fetch(getPluginServerRoute(getState()) + '/config', {
+        credentials: 'same-origin',
+        headers: {
+            'X-Requested-With': 'XMLHttpRequest',
+        },
+    });
+    if (!response.ok) {
+        return;
+    }
+
+    const config = await response.json();
+    dispatch({
+        type: RECEIVED_TYPES,
+        types: config.types,
+    });
+};
+
 export const create = (type, title, body, postID) => async (dispatch, getState) => {
     fetch(getPluginServerRoute(getState()) + '/create', {
         method: 'POST',
@@ -2,7 +2,7 @@ import {connect} from 'react-redux';
 import {bindActionCreators} from 'redux';
 
 import {closeRootModal, create} from 'actions';
-import {isRootModalVisible, getMessage, getPostID} from 'selectors';
+import {isRootModalVisible, getMessage, getPostID, getTypes} from 'selectors';
 
 import Root from './root';
 
@@ -10,6 +10,7 @@ const mapStateToProps = (state) => ({
     visible: isRootModalVisible(state),
     message: getMessage(state),
     postID: getPostID(state),
+    types: getTypes(state),
 });
 
 const mapDispatchToProps = (dispatch) => bindActionCreators({
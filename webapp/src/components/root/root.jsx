@@ -12,6 +12,10 @@ export default class Root extends React.Component {
         visible: PropTypes.bool.isRequired,
         message: PropTypes.string.isRequired,
         postID: PropTypes.string.isRequired,
+        types: PropTypes.arrayOf(PropTypes.shape({
+            type: PropTypes.string.isRequired,
+            display_name: PropTypes.string.isRequired,
+        })).isRequired,
         close: PropTypes.func.isRequired,
         submit: PropTypes.func.isRequired,
         theme: PropTypes.object.isRequired,
@@ -44,7 +48,7 @@ export default class Root extends React.Component {
     }
 
     render() {
-        const {visible, theme, close} = this.props;
+        const {visible, theme, close, types} = this.props;
 
         if (!visible) {
             return null;
@@ -72,39 +76,22 @@ export default class Root extends React.Component {
                             key='channelType'
                             className='multi-select__radio'
                         >
-                            <div className='radio'>
-                                <label>
-                                    <input
-                                        id='admin'
-                                        type='radio'
-                                        checked={type === 'admin'}
-                                        onChange={() => this.setState({type: 'admin'})}
-                                    />
-                                    {'Admin'}
-                                </label>
-                            </div>
-                            <div className='radio'>
-                                <label>
-                                    <input
-                                        id='developer'
-                                        type='radio'
-                                        checked={type === 'developer'}
-                                        onChange={() => this.setState({type: 'developer'})}
-                                    />
-                                    {'Developer'}
-                                </label>
-                            </div>
-                            <div className='radio'>
-                                <label>
-                                    <input
-                                        id='handbook'
-                                        type='radio'
-                                        checked={type === 'handbook'}
-                                        onChange={() => this.setState({type: 'handbook'})}
-                                    />
-                                    {'Company Handbook'}
-                                </label>
-                            </div>
+                            {types.map((t) => (
+                                <div
+                                    key={t.type}
+                                    className='radio'
+                                >
+                                    <label>
+                                        <input
+                                            id={t.type}
+                                            type='radio'
+                                            checked={type === t.type}
+                                            onChange={() => this.setState({type: t.type})}
+                                        />
+                                        {t.display_name}
+                                    </label>
+                                </div>
+                            ))}
                         </fieldset>
                     </div>
                     <div className='docup-item'>
@@ -1,6 +1,6 @@
 import {combineReducers} from 'redux';
 
-import {OPEN_ROOT_MODAL, CLOSE_ROOT_MODAL} from './action_types';
+import {OPEN_ROOT_MODAL, CLOSE_ROOT_MODAL, RECEIVED_TYPES} from './action_types';
 
 const rootModalVisible = (state = false, action) => {
     switch (action.type) {
@@ -24,8 +24,18 @@ const postID = (state = '', action) => {
     }
 };
 
+const types = (state = [], action) => {
+    switch (action.type) {
+    case RECEIVED_TYPES:
+        return action.types;
+    default:
+        return state;
+    }
+};
+
 export default combineReducers({
     rootModalVisible,
     postID,
+    types,
 });
 
@@ -4,6 +4,7 @@ const getPluginState = (state) => state['plugins-' + pluginId] || {};
 
 export const isRootModalVisible = (state) => getPluginState(state).rootModalVisible;
 export const getPostID = (state) => getPluginState(state).postID;
+export const getTypes = (state) => getPluginState(state).types || [];
 export const getMessage = (state) => {
     const postID = getPluginState(state).postID;
     if (!postID) {